@@ -0,0 +1,175 @@
+// Package accrual 按已索引事件在链下重放 Staking 合约的奖励计算,
+// 与合约的 rewardPerToken/earned/updateReward 保持逐步一致 (uint256 整数除法)
+package accrual
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var wad = big.NewInt(1e18)
+
+// DefaultRewardRate 合约构造时的 rewardRate: 1e18 / (60*60*60*60)
+var DefaultRewardRate = new(big.Int).Div(wad, big.NewInt(60*60*60*60))
+
+type EventKind int
+
+const (
+	Staked EventKind = iota
+	Withdrawn
+	RewardsClaimed
+	RewardRateUpdated
+)
+
+// Event 重放所需的事件字段, RewardRateUpdated 的 Amount 为新的 rewardRate
+type Event struct {
+	Kind        EventKind
+	BlockNumber uint64
+	LogIndex    uint
+	Timestamp   uint64
+	User        string
+	Amount      *big.Int
+}
+
+// UserState 对应合约的 stakedBalance / userRewardPerTokenPaid / rewards
+type UserState struct {
+	StakedBalance          *big.Int
+	UserRewardPerTokenPaid *big.Int
+	Rewards                *big.Int
+}
+
+// Engine 合约状态的链下副本, 事件必须按 (区块, 日志索引) 顺序 Apply
+type Engine struct {
+	rewardRate           *big.Int
+	totalStaked          *big.Int
+	rewardPerTokenStored *big.Int
+	lastUpdateTime       uint64
+	users                map[string]*UserState
+}
+
+func NewEngine(initialRewardRate *big.Int) *Engine {
+	return &Engine{
+		rewardRate:           new(big.Int).Set(initialRewardRate),
+		totalStaked:          new(big.Int),
+		rewardPerTokenStored: new(big.Int),
+		users:                make(map[string]*UserState),
+	}
+}
+
+// Apply 重放一条事件; 时间早于 lastUpdateTime 时合约的 SafeMath.sub 会回滚, 这里视为乱序
+func (e *Engine) Apply(ev Event) error {
+	if ev.Timestamp < e.lastUpdateTime {
+		return fmt.Errorf("event at block %d is older than last update time %d", ev.BlockNumber, e.lastUpdateTime)
+	}
+	if ev.Amount == nil {
+		return errors.New("event amount is nil")
+	}
+	switch ev.Kind {
+	case Staked:
+		e.updateReward(ev.User, ev.Timestamp)
+		user := e.user(ev.User)
+		e.totalStaked.Add(e.totalStaked, ev.Amount)
+		user.StakedBalance.Add(user.StakedBalance, ev.Amount)
+	case Withdrawn:
+		e.updateReward(ev.User, ev.Timestamp)
+		user := e.user(ev.User)
+		if user.StakedBalance.Cmp(ev.Amount) < 0 {
+			return fmt.Errorf("withdrawn %s exceeds staked balance of %s", ev.Amount, ev.User)
+		}
+		e.totalStaked.Sub(e.totalStaked, ev.Amount)
+		user.StakedBalance.Sub(user.StakedBalance, ev.Amount)
+	case RewardsClaimed:
+		e.updateReward(ev.User, ev.Timestamp)
+		e.user(ev.User).Rewards.SetInt64(0)
+	case RewardRateUpdated:
+		// updateRewardRate 没有 updateReward 修饰, 新速率从 lastUpdateTime 起生效
+		e.rewardRate.Set(ev.Amount)
+	default:
+		return fmt.Errorf("unknown event kind %d", ev.Kind)
+	}
+	return nil
+}
+
+// RewardPerToken 合约 rewardPerToken() 在时间 at 的返回值
+func (e *Engine) RewardPerToken(at uint64) *big.Int {
+	if e.totalStaked.Sign() == 0 {
+		return new(big.Int).Set(e.rewardPerTokenStored)
+	}
+	totalRewards := new(big.Int).Mul(e.rewardRate, new(big.Int).SetUint64(at-e.lastUpdateTime))
+	totalRewards.Mul(totalRewards, wad)
+	totalRewards.Div(totalRewards, e.totalStaked)
+	return totalRewards.Add(totalRewards, e.rewardPerTokenStored)
+}
+
+// Earned 合约 earned(account) 在时间 at 的返回值
+func (e *Engine) Earned(account string, at uint64) *big.Int {
+	user, ok := e.users[account]
+	if !ok {
+		return new(big.Int)
+	}
+	return e.earned(user, e.RewardPerToken(at))
+}
+
+// User 返回用户当前的合约状态副本
+func (e *Engine) User(account string) UserState {
+	user, ok := e.users[account]
+	if !ok {
+		return UserState{StakedBalance: new(big.Int), UserRewardPerTokenPaid: new(big.Int), Rewards: new(big.Int)}
+	}
+	return UserState{
+		StakedBalance:          new(big.Int).Set(user.StakedBalance),
+		UserRewardPerTokenPaid: new(big.Int).Set(user.UserRewardPerTokenPaid),
+		Rewards:                new(big.Int).Set(user.Rewards),
+	}
+}
+
+// Accounts 出现过的全部账户
+func (e *Engine) Accounts() []string {
+	accounts := make([]string, 0, len(e.users))
+	for account := range e.users {
+		accounts = append(accounts, account)
+	}
+	return accounts
+}
+
+func (e *Engine) RewardRate() *big.Int {
+	return new(big.Int).Set(e.rewardRate)
+}
+
+func (e *Engine) TotalStaked() *big.Int {
+	return new(big.Int).Set(e.totalStaked)
+}
+
+func (e *Engine) RewardPerTokenStored() *big.Int {
+	return new(big.Int).Set(e.rewardPerTokenStored)
+}
+
+func (e *Engine) LastUpdateTime() uint64 {
+	return e.lastUpdateTime
+}
+
+// updateReward 对应合约的 updateReward 修饰器
+func (e *Engine) updateReward(account string, now uint64) {
+	e.rewardPerTokenStored = e.RewardPerToken(now)
+	e.lastUpdateTime = now
+	user := e.user(account)
+	user.Rewards = e.earned(user, e.rewardPerTokenStored)
+	user.UserRewardPerTokenPaid = new(big.Int).Set(e.rewardPerTokenStored)
+}
+
+func (e *Engine) earned(user *UserState, rewardPerToken *big.Int) *big.Int {
+	delta := new(big.Int).Sub(rewardPerToken, user.UserRewardPerTokenPaid)
+	delta.Mul(delta, user.StakedBalance)
+	delta.Div(delta, wad)
+	return delta.Add(delta, user.Rewards)
+}
+
+func (e *Engine) user(account string) *UserState {
+	user, ok := e.users[account]
+	if !ok {
+		user = &UserState{StakedBalance: new(big.Int), UserRewardPerTokenPaid: new(big.Int), Rewards: new(big.Int)}
+		e.users[account] = user
+	}
+	return user
+}
@@ -0,0 +1,192 @@
+package accrual
+
+import (
+	"context"
+	"go-solidity-staking/gen/erc20"
+	"go-solidity-staking/gen/staking"
+	"go-solidity-staking/internal/testchain"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// chainFixture 模拟链上部署的 Staking 合约, 与按回执日志重放的 Engine 对照
+type chainFixture struct {
+	t        *testing.T
+	ctx      context.Context
+	sim      *simulated.Backend
+	client   simulated.Client
+	contract *staking.Staking
+	address  common.Address
+	engine   *Engine
+	accounts []common.Address
+}
+
+// TestEngineMatchesOnChainEarned 在受控时间点 stake/withdraw/getReward/updateRewardRate,
+// 每一步 Engine.Earned 都必须等于合约 earned
+func TestEngineMatchesOnChainEarned(t *testing.T) {
+	ctx := context.Background()
+	owner := crypto.PubkeyToAddress(testchain.OwnerKey.PublicKey)
+	other := crypto.PubkeyToAddress(testchain.OtherKey.PublicKey)
+	ether := testchain.Ether
+	sim := testchain.NewBackend(t, testchain.OwnerKey, testchain.OtherKey)
+	client := sim.Client()
+	ownerAuth := testchain.Transactor(t, ctx, client, testchain.OwnerKey)
+	otherAuth := testchain.Transactor(t, ctx, client, testchain.OtherKey)
+
+	supply := new(big.Int).Mul(big.NewInt(1000000), ether)
+	stakingTokenAddress, _, stakingToken, err := erc20.DeployErc20(ownerAuth, client, "Stake", "STK", supply)
+	if err != nil {
+		t.Fatalf("deploy staking token: %v", err)
+	}
+	rewardTokenAddress, _, rewardToken, err := erc20.DeployErc20(ownerAuth, client, "Reward", "RWD", supply)
+	if err != nil {
+		t.Fatalf("deploy reward token: %v", err)
+	}
+	sim.Commit()
+	address, _, contract, err := staking.DeployStaking(ownerAuth, client, stakingTokenAddress, rewardTokenAddress, owner)
+	if err != nil {
+		t.Fatalf("deploy staking: %v", err)
+	}
+	sim.Commit()
+
+	// 合约持有全部奖励代币, 两个账户各持有质押代币并授权
+	funding := new(big.Int).Mul(big.NewInt(1000), ether)
+	mustSend(t, func() (*types.Transaction, error) { return rewardToken.Transfer(ownerAuth, address, supply) })
+	mustSend(t, func() (*types.Transaction, error) { return stakingToken.Transfer(ownerAuth, other, funding) })
+	mustSend(t, func() (*types.Transaction, error) { return stakingToken.Approve(ownerAuth, address, supply) })
+	mustSend(t, func() (*types.Transaction, error) { return stakingToken.Approve(otherAuth, address, supply) })
+	sim.Commit()
+
+	f := &chainFixture{
+		t:        t,
+		ctx:      ctx,
+		sim:      sim,
+		client:   client,
+		contract: contract,
+		address:  address,
+		engine:   NewEngine(DefaultRewardRate),
+		accounts: []common.Address{owner, other},
+	}
+	amount := func(n int64) *big.Int { return new(big.Int).Mul(big.NewInt(n), ether) }
+
+	f.step("owner stake", func() (*types.Transaction, error) { return contract.Stake(ownerAuth, amount(100)) })
+	f.wait(time.Hour)
+	f.step("other stake", func() (*types.Transaction, error) { return contract.Stake(otherAuth, amount(300)) })
+	f.wait(90 * time.Minute)
+	f.step("owner withdraw", func() (*types.Transaction, error) { return contract.WithdrawStakedTokens(ownerAuth, amount(40)) })
+	f.wait(2 * time.Hour)
+	f.step("other claim", func() (*types.Transaction, error) { return contract.GetReward(otherAuth) })
+	f.wait(3 * time.Hour)
+	// updateRewardRate 不结算, 新速率对 lastUpdateTime 之后的整段时间生效
+	f.step("update reward rate", func() (*types.Transaction, error) {
+		return contract.UpdateRewardRate(ownerAuth, new(big.Int).Mul(DefaultRewardRate, big.NewInt(7)))
+	})
+	f.wait(time.Hour)
+	f.step("owner claim", func() (*types.Transaction, error) { return contract.GetReward(ownerAuth) })
+	f.wait(30 * time.Minute)
+	f.step("other withdraw all", func() (*types.Transaction, error) { return contract.WithdrawStakedTokens(otherAuth, amount(300)) })
+	f.wait(time.Hour)
+	f.step("lower reward rate", func() (*types.Transaction, error) { return contract.UpdateRewardRate(ownerAuth, DefaultRewardRate) })
+	f.wait(45 * time.Minute)
+	f.step("owner withdraw rest", func() (*types.Transaction, error) { return contract.WithdrawStakedTokens(ownerAuth, amount(60)) })
+	f.wait(time.Hour)
+
+	if f.engine.TotalStaked().Sign() != 0 {
+		t.Fatalf("total staked %s after full withdrawal, want 0", f.engine.TotalStaked())
+	}
+}
+
+// step 发送交易并出块, 按回执日志重放后对照链上 earned
+func (f *chainFixture) step(name string, send func() (*types.Transaction, error)) {
+	f.t.Helper()
+	tx := mustSend(f.t, send)
+	f.sim.Commit()
+	receipt, err := f.client.TransactionReceipt(f.ctx, tx.Hash())
+	if err != nil {
+		f.t.Fatalf("%s: receipt: %v", name, err)
+	}
+	if receipt.Status != types.ReceiptStatusSuccessful {
+		f.t.Fatalf("%s: transaction reverted", name)
+	}
+	header, err := f.client.HeaderByNumber(f.ctx, receipt.BlockNumber)
+	if err != nil {
+		f.t.Fatalf("%s: header: %v", name, err)
+	}
+	for _, logEntry := range receipt.Logs {
+		if logEntry.Address != f.address {
+			continue
+		}
+		ev, err := f.parseEvent(*logEntry, header.Time)
+		if err != nil {
+			f.t.Fatalf("%s: parse log: %v", name, err)
+		}
+		if err := f.engine.Apply(ev); err != nil {
+			f.t.Fatalf("%s: apply: %v", name, err)
+		}
+	}
+	f.assertEarned(name, header)
+}
+
+// wait 推进链上时间并出一个空块, 对照两次事件之间的累计
+func (f *chainFixture) wait(d time.Duration) {
+	f.t.Helper()
+	if err := f.sim.AdjustTime(d); err != nil {
+		f.t.Fatalf("adjust time: %v", err)
+	}
+	header, err := f.client.HeaderByNumber(f.ctx, nil)
+	if err != nil {
+		f.t.Fatalf("head: %v", err)
+	}
+	f.assertEarned("after "+d.String(), header)
+}
+
+func (f *chainFixture) assertEarned(name string, header *types.Header) {
+	f.t.Helper()
+	for _, account := range f.accounts {
+		onchain, err := f.contract.Earned(&bind.CallOpts{Context: f.ctx, BlockNumber: header.Number}, account)
+		if err != nil {
+			f.t.Fatalf("%s: earned: %v", name, err)
+		}
+		if got := f.engine.Earned(account.Hex(), header.Time); got.Cmp(onchain) != 0 {
+			f.t.Fatalf("%s: block %d earned of %s = %s, on-chain %s", name, header.Number, account.Hex(), got, onchain)
+		}
+	}
+}
+
+func (f *chainFixture) parseEvent(logEntry types.Log, timestamp uint64) (Event, error) {
+	ev := Event{BlockNumber: logEntry.BlockNumber, LogIndex: logEntry.Index, Timestamp: timestamp}
+	if staked, err := f.contract.ParseStaked(logEntry); err == nil {
+		ev.Kind, ev.User, ev.Amount = Staked, staked.User.Hex(), staked.Amount
+		return ev, nil
+	}
+	if withdrawn, err := f.contract.ParseWithdrawn(logEntry); err == nil {
+		ev.Kind, ev.User, ev.Amount = Withdrawn, withdrawn.User.Hex(), withdrawn.Amount
+		return ev, nil
+	}
+	if claimed, err := f.contract.ParseRewardsClaimed(logEntry); err == nil {
+		ev.Kind, ev.User, ev.Amount = RewardsClaimed, claimed.User.Hex(), claimed.Amount
+		return ev, nil
+	}
+	updated, err := f.contract.ParseRewardRateUpdated(logEntry)
+	if err != nil {
+		return ev, err
+	}
+	ev.Kind, ev.Amount = RewardRateUpdated, updated.NewRewardRate
+	return ev, nil
+}
+
+func mustSend(t *testing.T, send func() (*types.Transaction, error)) *types.Transaction {
+	t.Helper()
+	tx, err := send()
+	if err != nil {
+		t.Fatalf("send transaction: %v", err)
+	}
+	return tx
+}
@@ -2,10 +2,14 @@ package bootstrap
 
 import (
 	"context"
+	"fmt"
 	"go-solidity-staking/handle"
 	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
 	"go-solidity-staking/routers"
 	"go-solidity-staking/service"
+	"math/big"
+	"strings"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -17,6 +21,7 @@ import (
 
 func NewApp() (*gin.Engine, error) {
 	logger.Init()
+	models.Init()
 	config, err := ini.Load("./config/staking.ini")
 	if err != nil {
 		logger.WithModule("bootstrap").WithError(err).Error("load config failed")
@@ -27,7 +32,14 @@ func NewApp() (*gin.Engine, error) {
 		logger.WithModule("bootstrap").WithError(err).Error("dial ws failed")
 		return nil, err
 	}
-	listenerService := service.NewListenerService(wsClient)
+	// 通用合约的 ABI 目录, 文件名 <name>.abi
+	abiStore := service.NewFileAbiStore(config.Section("eth").Key("abi_dir").MustString("./build"))
+	listenerService := service.NewListenerService(wsClient, service.ChunkConfig{
+		Initial:     config.Section("eth").Key("batch_size").MustUint64(service.DefaultChunkConfig.Initial),
+		Min:         config.Section("eth").Key("min_batch_size").MustUint64(service.DefaultChunkConfig.Min),
+		Max:         config.Section("eth").Key("max_batch_size").MustUint64(service.DefaultChunkConfig.Max),
+		Concurrency: config.Section("eth").Key("backfill_concurrency").MustInt(service.DefaultChunkConfig.Concurrency),
+	}, abiStore)
 	contractAddress := common.HexToAddress(config.Section("eth").Key("contract_address").String())
 	stakingTokenAddressStr := config.Section("eth").Key("staking_token").String()
 	stakingTokenAddress := common.HexToAddress(stakingTokenAddressStr)
@@ -46,52 +58,152 @@ func NewApp() (*gin.Engine, error) {
 	tokenService := service.NewERC20TokenService(rpcClient)
 	tokenHandle := handle.NewERC20Handler(tokenService)
 
-	go func() {
-		// 调用区块链回放
-		if err := listenerService.ReplayFromLast(
-			context.Background(),
-			contractAddress,
-			config.Section("eth").Key("start_block").MustUint64(0),
-			config.Section("eth").Key("confirmations").MustUint64(1),
-		); err != nil {
-			logger.WithModule("listener").WithError(err).Error("replay from last failed")
-			return
-		}
-		listenerService.StartReplayLoop(
-			context.Background(),
-			contractAddress,
-			config.Section("eth").Key("start_block").MustUint64(0),
-			config.Section("eth").Key("confirmations").MustUint64(1),
-			time.Duration(config.Section("eth").Key("interval").MustUint64(1))*time.Second,
-		)
-	}()
-	funcERC20(stakingTokenAddressStr, stakingTokenAddress, listenerService, config)
-	funcERC20(rewardTokenAddressStr, rewardTokenAddress, listenerService, config)
+	// ERC20 持有人余额账本
+	holderHandle := handle.NewHolderHandle(service.NewHolderService())
+	// ERC20 授权额度账本, 风险授权报告缺省检查质押合约
+	allowanceHandle := handle.NewAllowanceHandle(service.NewAllowanceService(), contractAddress)
+
+	// 账户活动时间线, 合并质押与 ERC20 明细
+	activityHandle := handle.NewActivityHandle(service.NewActivityService(rpcClient))
+
+	// 事件明细 CSV / JSONL 导出
+	exportHandle := handle.NewExportHandle(service.NewExportService(rpcClient))
+
+	// 质押余额 Merkle 快照
+	merkleHandle := handle.NewMerkleHandle(service.NewMerkleService(rpcClient))
+
+	// 质押仓位投影
+	positionHandle := handle.NewPositionHandle(service.NewPositionService())
+
+	// 链下奖励重放
+	accrualHandle := handle.NewRewardAccrualHandle(service.NewRewardAccrualService())
+
+	// APR/APY
+	priceSource, err := newPriceSource(config.Section("price"), rpcClient)
+	if err != nil {
+		logger.WithModule("bootstrap").WithError(err).Error("init price source failed")
+		return nil, err
+	}
+	yieldHandle := handle.NewYieldHandle(service.NewYieldService(rpcClient, priceSource))
+
+	// 奖励池偿付能力监控
+	solvencySection := config.Section("solvency")
+	solvencyService := service.NewSolvencyService(rpcClient, service.SolvencyConfig{
+		LiabilitySource: solvencySection.Key("liability_source").MustString(service.LiabilityEngine),
+		WarningRunway:   time.Duration(solvencySection.Key("warning_runway_hours").MustUint64(168)) * time.Hour,
+		CriticalRunway:  time.Duration(solvencySection.Key("critical_runway_hours").MustUint64(24)) * time.Hour,
+		AlertWebhook:    solvencySection.Key("alert_webhook").String(),
+	})
+	solvencyHandle := handle.NewSolvencyHandle(solvencyService)
+	go solvencyService.StartLoop(
+		context.Background(),
+		time.Duration(solvencySection.Key("interval").MustUint64(300))*time.Second,
+	)
+
+	// TVL/活动时间序列
+	rollupService := service.NewRollupService()
+	rollupHandle := handle.NewRollupHandle(rollupService)
+	go rollupService.StartLoop(
+		context.Background(),
+		time.Duration(config.Section("eth").Key("rollup_interval").MustUint64(60))*time.Second,
+	)
+
+	// 死信重试
+	deadLetterService := service.NewDeadLetterService(listenerService, uint(config.Section("eth").Key("dead_letter_max_attempts").MustUint(10)))
+	deadLetterHandle := handle.NewDeadLetterHandle(deadLetterService)
+	go deadLetterService.StartRetryLoop(
+		context.Background(),
+		time.Duration(config.Section("eth").Key("dead_letter_interval").MustUint64(60))*time.Second,
+	)
+
+	// 合约注册表: 配置文件中的合约作为初始数据, 之后通过管理接口增删
+	startBlock := config.Section("eth").Key("start_block").MustUint64(0)
+	confirmations := config.Section("eth").Key("confirmations").MustUint64(1)
+	seeds := []models.WatchedContract{{Kind: service.ContractKindStaking, Address: contractAddress.Hex(), StartBlock: startBlock, Confirmations: confirmations, Enabled: true}}
+	seeds = appendERC20Seed(seeds, stakingTokenAddressStr, stakingTokenAddress, startBlock, confirmations)
+	seeds = appendERC20Seed(seeds, rewardTokenAddressStr, rewardTokenAddress, startBlock, confirmations)
+	registryService := service.NewRegistryService(listenerService, abiStore, service.SupervisorConfig{
+		Subscribe:          config.Section("eth").Key("mode").String() == "subscribe",
+		Interval:           time.Duration(config.Section("eth").Key("interval").MustUint64(1)) * time.Second,
+		CheckpointInterval: time.Duration(config.Section("eth").Key("checkpoint_interval").MustUint64(30)) * time.Second,
+		ReloadInterval:     time.Duration(config.Section("eth").Key("registry_reload_interval").MustUint64(30)) * time.Second,
+	})
+	if err := registryService.Seed(context.Background(), seeds); err != nil {
+		logger.WithModule("bootstrap").WithError(err).Error("seed watched contracts failed")
+		return nil, err
+	}
+	registryHandle := handle.NewRegistryHandle(registryService, abiStore)
+	go registryService.Start(context.Background())
+
+	// 链上状态对账
+	reconcilerService := service.NewReconcilerService(rpcClient, registryService, config.Section("eth").Key("reconcile_sample_size").MustInt(100))
+	reconcilerHandle := handle.NewReconcilerHandle(reconcilerService)
+	if interval := config.Section("eth").Key("reconcile_interval").MustUint64(600); interval > 0 {
+		go reconcilerService.StartLoop(context.Background(), time.Duration(interval)*time.Second)
+	}
 	r := gin.Default()
 	r.Use(cors.Default())
-	routers.ApiRoutersInit(r, stakingHandle, tokenHandle)
+	routers.ApiRoutersInit(r, stakingHandle, tokenHandle, positionHandle, accrualHandle, rollupHandle, yieldHandle, solvencyHandle, holderHandle, allowanceHandle, activityHandle, exportHandle, merkleHandle)
+	routers.AdminRoutersInit(r, deadLetterHandle, registryHandle, positionHandle, rollupHandle, reconcilerHandle, holderHandle, allowanceHandle, merkleHandle)
 	return r, nil
 }
 
-func funcERC20(addressStr string, tokenAddress common.Address, listenerService service.ListenerService, config *ini.File) {
+func appendERC20Seed(seeds []models.WatchedContract, addressStr string, tokenAddress common.Address, startBlock uint64, confirmations uint64) []models.WatchedContract {
 	if addressStr != "" && tokenAddress != (common.Address{}) {
-		go func() {
-			if err := listenerService.ReplayERC20FromLast(
-				context.Background(),
-				tokenAddress,
-				config.Section("eth").Key("start_block").MustUint64(0),
-				config.Section("eth").Key("confirmations").MustUint64(1),
-			); err != nil {
-				logger.WithModule("listener").WithError(err).Error("replay erc20 from last failed")
-				return
+		return append(seeds, models.WatchedContract{
+			Kind:          service.ContractKindERC20,
+			Address:       tokenAddress.Hex(),
+			StartBlock:    startBlock,
+			Confirmations: confirmations,
+			Enabled:       true,
+		})
+	}
+	return seeds
+}
+
+// newPriceSource 按 [price] source 创建价格来源: static / oracle / http
+func newPriceSource(section *ini.Section, client *ethclient.Client) (service.PriceSource, error) {
+	switch source := section.Key("source").MustString(service.PriceSourceStatic); source {
+	case service.PriceSourceStatic:
+		prices := make(map[common.Address]*big.Float)
+		for token, value := range parseAddressPairs(section.Key("static_prices").String()) {
+			price, ok := new(big.Float).SetString(value)
+			if !ok {
+				return nil, fmt.Errorf("invalid static price %q for %s", value, token.Hex())
+			}
+			prices[token] = price
+		}
+		return service.NewStaticPriceSource(prices), nil
+	case service.PriceSourceOracle:
+		feeds := make(map[common.Address]common.Address)
+		for token, feed := range parseAddressPairs(section.Key("oracle_feeds").String()) {
+			if !common.IsHexAddress(feed) {
+				return nil, fmt.Errorf("invalid price feed %q for %s", feed, token.Hex())
 			}
-			listenerService.StartERC20ReplayLoop(
-				context.Background(),
-				tokenAddress,
-				config.Section("eth").Key("start_block").MustUint64(0),
-				config.Section("eth").Key("confirmations").MustUint64(1),
-				time.Duration(config.Section("eth").Key("interval").MustUint64(1))*time.Second,
-			)
-		}()
+			feeds[token] = common.HexToAddress(feed)
+		}
+		maxAge := time.Duration(section.Key("oracle_max_age").MustUint64(0)) * time.Second
+		return service.NewOraclePriceSource(client, feeds, maxAge), nil
+	case service.PriceSourceHTTP:
+		url := section.Key("http_url").String()
+		if url == "" {
+			return nil, fmt.Errorf("price http_url is required")
+		}
+		return service.NewHTTPPriceSource(url, time.Duration(section.Key("http_timeout").MustUint64(5))*time.Second), nil
+	default:
+		return nil, fmt.Errorf("unsupported price source %q", source)
+	}
+}
+
+// parseAddressPairs 解析 "地址:值,地址:值"
+func parseAddressPairs(value string) map[common.Address]string {
+	pairs := make(map[common.Address]string)
+	for _, item := range strings.Split(value, ",") {
+		address, v, ok := strings.Cut(strings.TrimSpace(item), ":")
+		if !ok || !common.IsHexAddress(address) {
+			continue
+		}
+		pairs[common.HexToAddress(address)] = strings.TrimSpace(v)
 	}
+	return pairs
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"io"
+	"log"
+	"os"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gopkg.in/ini.v1"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 导出事件明细, 不传 -out 时写到标准输出:
+// go run ./deploy/export -event rewards_claimed -format csv [-contract 0x...] [-address 0x...] [-from 100] [-to 200] [-from-time ...] [-to-time ...] [-out claims.csv]
+func main() {
+	event := flag.String("event", "", "event to export: staked/withdrawn/rewards_claimed/reward_rate_updated/ownership_transferred/transfer/approval")
+	format := flag.String("format", service.ExportCSV, "output format csv/jsonl")
+	contract := flag.String("contract", "", "only rows of this contract")
+	address := flag.String("address", "", "only rows involving this address")
+	from := flag.Int64("from", -1, "first block (inclusive)")
+	to := flag.Int64("to", -1, "last block (inclusive)")
+	fromTime := flag.Int64("from-time", -1, "first block time in unix seconds (inclusive)")
+	toTime := flag.Int64("to-time", -1, "last block time in unix seconds (inclusive)")
+	outPath := flag.String("out", "", "output file, stdout when empty")
+	flag.Parse()
+
+	query := service.ExportQuery{
+		Event:     *event,
+		Format:    *format,
+		FromBlock: optionalUint64(*from),
+		ToBlock:   optionalUint64(*to),
+		FromTime:  optionalUint64(*fromTime),
+		ToTime:    optionalUint64(*toTime),
+	}
+	for _, param := range []struct {
+		value  string
+		target **common.Address
+	}{
+		{*contract, &query.Contract},
+		{*address, &query.Address},
+	} {
+		if param.value == "" {
+			continue
+		}
+		if !common.IsHexAddress(param.value) {
+			log.Fatalf("invalid address:%q", param.value)
+		}
+		parsed := common.HexToAddress(param.value)
+		*param.target = &parsed
+	}
+
+	logger.Init()
+	models.Init()
+	// SQL 日志默认写到标准输出, 会混入导出内容
+	models.DB.Logger = gormlogger.Discard
+	config, err := ini.Load("./config/staking.ini")
+	if err != nil {
+		log.Fatalf("ini load error:%v", err)
+	}
+	rpcUrl := config.Section("url").Key("rpc_url").String()
+	client, err := ethclient.Dial(rpcUrl)
+	if err != nil {
+		log.Fatalf(" ethclient.Dial error:%v", err)
+	}
+	exportService := service.NewExportService(client)
+	if err := exportService.Validate(query); err != nil {
+		log.Fatalf("invalid export:%v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *outPath != "" {
+		file, err := os.Create(*outPath)
+		if err != nil {
+			log.Fatalf("create output error:%v", err)
+		}
+		defer file.Close()
+		out = file
+	}
+	writer := bufio.NewWriter(out)
+	count, err := exportService.Export(context.Background(), writer, query)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if err != nil {
+		log.Fatalf("export error after %d rows:%v", count, err)
+	}
+	log.Printf("exported %d %s rows", count, *event)
+}
+
+func optionalUint64(value int64) *uint64 {
+	if value < 0 {
+		return nil
+	}
+	parsed := uint64(value)
+	return &parsed
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gopkg.in/ini.v1"
+)
+
+// 生成质押余额 Merkle 快照, 缺省取已索引的最新区块并与链上 stakedBalance 核对:
+// go run ./deploy/merkle -contract 0x... [-block 12345] [-skip-verify]
+func main() {
+	contract := flag.String("contract", "", "staking contract address")
+	block := flag.Int64("block", -1, "snapshot block, latest indexed block when negative")
+	skipVerify := flag.Bool("skip-verify", false, "skip the on-chain stakedBalance cross-check (needs an archive node for old blocks)")
+	flag.Parse()
+	if !common.IsHexAddress(*contract) {
+		log.Fatalf("invalid contract address:%q", *contract)
+	}
+
+	logger.Init()
+	models.Init()
+	config, err := ini.Load("./config/staking.ini")
+	if err != nil {
+		log.Fatalf("ini load error:%v", err)
+	}
+	rpcUrl := config.Section("url").Key("rpc_url").String()
+	client, err := ethclient.Dial(rpcUrl)
+	if err != nil {
+		log.Fatalf(" ethclient.Dial error:%v", err)
+	}
+	var blockNumber *uint64
+	if *block >= 0 {
+		parsed := uint64(*block)
+		blockNumber = &parsed
+	}
+	snapshot, err := service.NewMerkleService(client).Generate(context.Background(), common.HexToAddress(*contract), blockNumber, !*skipVerify)
+	if err != nil {
+		log.Fatalf("merkle snapshot error:%v", err)
+	}
+	log.Printf("snapshot %d of %s at block %d: root %s, %d leaves, total staked %s, verified %v",
+		snapshot.ID, snapshot.Contract, snapshot.BlockNumber, snapshot.Root, snapshot.LeafCount, snapshot.TotalStaked, snapshot.Verified)
+}
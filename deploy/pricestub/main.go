@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+type priceFlags map[common.Address]string
+
+func (p priceFlags) String() string {
+	return ""
+}
+
+func (p priceFlags) Set(value string) error {
+	address, price, _ := strings.Cut(value, "=")
+	p[common.HexToAddress(address)] = price
+	return nil
+}
+
+// 本地价格接口 stub, 配合 [price] source = http:
+// go run ./deploy/pricestub -addr :9000 -price 0x...=1.5 -price 0x...=0.2
+func main() {
+	addr := flag.String("addr", ":9000", "listen address")
+	prices := priceFlags{}
+	flag.Var(prices, "price", "token=price, repeatable")
+	flag.Parse()
+
+	http.HandleFunc("/price/", func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.URL.Path, "/price/")
+		price, ok := prices[common.HexToAddress(token)]
+		if !common.IsHexAddress(token) || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"price": price})
+	})
+	log.Printf("price stub listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"log"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"gopkg.in/ini.v1"
+)
+
+// 重新索引合约的历史区间, 不修改 sync_state, 可在服务运行时执行:
+// go run ./deploy/reindex -contract 0x... -from 100 -to 200 [-purge]
+func main() {
+	contract := flag.String("contract", "", "contract address (must be registered unless -kind is set)")
+	from := flag.Uint64("from", 0, "first block")
+	to := flag.Uint64("to", 0, "last block")
+	purge := flag.Bool("purge", false, "delete indexed rows in the range before reindexing")
+	kind := flag.String("kind", "", "contract kind staking/erc20/abi, skips the registry lookup")
+	abiName := flag.String("abi", "", "abi name when -kind=abi")
+	confirmations := flag.Uint64("confirmations", 0, "confirmations when -kind is set, defaults to eth.confirmations")
+	flag.Parse()
+	if !common.IsHexAddress(*contract) {
+		log.Fatalf("invalid contract address:%q", *contract)
+	}
+
+	logger.Init()
+	models.Init()
+	config, err := ini.Load("./config/staking.ini")
+	if err != nil {
+		log.Fatalf("ini load error:%v", err)
+	}
+	rpcUrl := config.Section("url").Key("rpc_url").String()
+	client, err := ethclient.Dial(rpcUrl)
+	if err != nil {
+		log.Fatalf(" ethclient.Dial error:%v", err)
+	}
+	abiStore := service.NewFileAbiStore(config.Section("eth").Key("abi_dir").MustString("./build"))
+	listener := service.NewListenerService(client, service.ChunkConfig{
+		Initial:     config.Section("eth").Key("batch_size").MustUint64(service.DefaultChunkConfig.Initial),
+		Min:         config.Section("eth").Key("min_batch_size").MustUint64(service.DefaultChunkConfig.Min),
+		Max:         config.Section("eth").Key("max_batch_size").MustUint64(service.DefaultChunkConfig.Max),
+		Concurrency: config.Section("eth").Key("backfill_concurrency").MustInt(service.DefaultChunkConfig.Concurrency),
+	}, abiStore)
+
+	address := common.HexToAddress(*contract)
+	ctx := context.Background()
+	if *kind != "" {
+		target := service.WatchTarget{Kind: *kind, Address: address, AbiName: *abiName}
+		if *confirmations == 0 {
+			*confirmations = config.Section("eth").Key("confirmations").MustUint64(1)
+		}
+		err = listener.ReindexRange(ctx, target, *from, *to, *confirmations, *purge)
+	} else {
+		registry := service.NewRegistryService(listener, abiStore, service.SupervisorConfig{})
+		err = registry.Reindex(ctx, address, *from, *to, *purge)
+	}
+	if err != nil {
+		log.Fatalf("reindex error:%v", err)
+	}
+	log.Printf("reindex %s blocks %d-%d finished", address.Hex(), *from, *to)
+}
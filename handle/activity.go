@@ -0,0 +1,42 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+type ActivityHandle struct {
+	svc service.ActivityService
+}
+
+func NewActivityHandle(svc service.ActivityService) *ActivityHandle {
+	return &ActivityHandle{svc: svc}
+}
+
+// Timeline 地址在所有合约上的活动, 按区块倒序, 传入上一页的 nextCursor 翻页
+func (h *ActivityHandle) Timeline(ctx *gin.Context) {
+	if !common.IsHexAddress(ctx.Query("account")) {
+		models.Error(ctx, "Error parsing account")
+		return
+	}
+	account := common.HexToAddress(ctx.Query("account"))
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 {
+		limit = 20
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+	page, err := h.svc.Timeline(ctx.Request.Context(), account, ctx.Query("cursor"), limit)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("account activity failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, page)
+}
@@ -0,0 +1,95 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type AllowanceHandle struct {
+	svc            service.AllowanceService
+	defaultSpender common.Address
+}
+
+// NewAllowanceHandle defaultSpender 为风险授权报告缺省检查的被授权人 (配置中的质押合约)
+func NewAllowanceHandle(svc service.AllowanceService, defaultSpender common.Address) *AllowanceHandle {
+	return &AllowanceHandle{svc: svc, defaultSpender: defaultSpender}
+}
+
+// ListByOwner 授权人的全部非零授权, contractAddress 可选
+func (a *AllowanceHandle) ListByOwner(ctx *gin.Context) {
+	if !common.IsHexAddress(ctx.Query("ownerAddress")) {
+		models.Error(ctx, "Error parsing ownerAddress")
+		return
+	}
+	owner := common.HexToAddress(ctx.Query("ownerAddress"))
+	contractAddress, ok := optionalAddress(ctx, "contractAddress")
+	if !ok {
+		models.Error(ctx, "Error parsing contractAddress")
+		return
+	}
+	pageNum, pageSize := parsePage(ctx)
+	list, total, err := a.svc.ListByOwner(ctx.Request.Context(), contractAddress, owner, pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list allowances failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
+
+// Risky 对 spenderAddress (缺省为质押合约) 的无限授权或超过 staleDays 天未授权也未使用的授权
+func (a *AllowanceHandle) Risky(ctx *gin.Context) {
+	spender := a.defaultSpender
+	if value := ctx.Query("spenderAddress"); value != "" {
+		if !common.IsHexAddress(value) {
+			models.Error(ctx, "Error parsing spenderAddress")
+			return
+		}
+		spender = common.HexToAddress(value)
+	}
+	staleDays, err := strconv.ParseUint(ctx.DefaultQuery("staleDays", "90"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing staleDays")
+		return
+	}
+	contractAddress, ok := optionalAddress(ctx, "contractAddress")
+	if !ok {
+		models.Error(ctx, "Error parsing contractAddress")
+		return
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":  "risky_approvals",
+		"spender": spender.Hex(),
+	}).Info("risky approvals request")
+	pageNum, pageSize := parsePage(ctx)
+	staleAfter := time.Duration(staleDays) * 24 * time.Hour
+	list, total, err := a.svc.Risky(ctx.Request.Context(), contractAddress, spender, staleAfter, pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("risky approvals failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
+
+// Rebuild 按已索引的 Approval/Transfer 明细重算授权额度
+func (a *AllowanceHandle) Rebuild(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.PostForm("contractAddress"))
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "rebuild_allowances",
+		"contract": contractAddress.Hex(),
+	}).Info("rebuild allowances request")
+	if err := a.svc.Rebuild(ctx.Request.Context(), contractAddress); err != nil {
+		logger.WithModule("api").WithError(err).Error("rebuild allowances failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx)
+}
@@ -0,0 +1,68 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type DeadLetterHandle struct {
+	svc service.DeadLetterService
+}
+
+func NewDeadLetterHandle(svc service.DeadLetterService) *DeadLetterHandle {
+	return &DeadLetterHandle{svc: svc}
+}
+
+func (d *DeadLetterHandle) List(ctx *gin.Context) {
+	status := ctx.DefaultQuery("status", models.DeadLetterPending)
+	pageNum, pageSize := parsePage(ctx)
+	list, total, err := d.svc.List(ctx.Request.Context(), status, pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list dead letters failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
+
+func (d *DeadLetterHandle) Retry(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.PostForm("id"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing id")
+		return
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action": "retry_dead_letter",
+		"id":     id,
+	}).Info("retry dead letter request")
+	entry, err := d.svc.Retry(ctx.Request.Context(), uint(id))
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("retry dead letter failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, entry)
+}
+
+func (d *DeadLetterHandle) Discard(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.PostForm("id"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing id")
+		return
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action": "discard_dead_letter",
+		"id":     id,
+	}).Info("discard dead letter request")
+	if err := d.svc.Discard(ctx.Request.Context(), uint(id)); err != nil {
+		logger.WithModule("api").WithError(err).Error("discard dead letter failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx)
+}
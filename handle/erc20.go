@@ -95,12 +95,18 @@ func (e *ERC20TokenHandle) Transfer(ctx *gin.Context) {
 func (e *ERC20TokenHandle) BalanceOf(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
 	to := common.HexToAddress(ctx.Query("to"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "balanceOf",
 		"contract": contractAddress.Hex(),
 		"to":       to.Hex(),
+		"block":    block.String(),
 	}).Info("balanceOf request")
-	balanceOf, err := e.svc.BalanceOf(ctx.Request.Context(), contractAddress, to)
+	balanceOf, err := e.svc.BalanceOf(ctx.Request.Context(), contractAddress, to, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("balanceOf failed")
 		models.Error(ctx, err.Error())
@@ -113,13 +119,19 @@ func (e *ERC20TokenHandle) Allowance(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
 	ownerAddress := common.HexToAddress(ctx.Query("ownerAddress"))
 	spenderAddress := common.HexToAddress(ctx.Query("spenderAddress"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "allowance",
 		"contract": contractAddress.Hex(),
 		"spender":  spenderAddress.Hex(),
 		"owner":    ownerAddress.Hex(),
+		"block":    block.String(),
 	}).Info("allowance request")
-	allowance, err := e.svc.Allowance(ctx.Request.Context(), contractAddress, ownerAddress, spenderAddress)
+	allowance, err := e.svc.Allowance(ctx.Request.Context(), contractAddress, ownerAddress, spenderAddress, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("allowance failed")
 		models.Error(ctx, err.Error())
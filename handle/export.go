@@ -0,0 +1,82 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"net/http"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ExportHandle struct {
+	svc service.ExportService
+}
+
+func NewExportHandle(svc service.ExportService) *ExportHandle {
+	return &ExportHandle{svc: svc}
+}
+
+// Export 以 CSV / JSONL 流式导出事件明细, 边查询边写出
+func (h *ExportHandle) Export(ctx *gin.Context) {
+	query := service.ExportQuery{
+		Event:  ctx.Query("event"),
+		Format: ctx.DefaultQuery("format", service.ExportCSV),
+	}
+	for _, param := range []struct {
+		name  string
+		value **common.Address
+	}{
+		{"contractAddress", &query.Contract},
+		{"address", &query.Address},
+	} {
+		parsed, ok := optionalAddress(ctx, param.name)
+		if !ok {
+			models.Error(ctx, "Error parsing "+param.name)
+			return
+		}
+		*param.value = parsed
+	}
+	for _, param := range []struct {
+		name  string
+		value **uint64
+	}{
+		{"fromBlock", &query.FromBlock},
+		{"toBlock", &query.ToBlock},
+		{"fromTime", &query.FromTime},
+		{"toTime", &query.ToTime},
+	} {
+		parsed, ok := optionalUint64(ctx, param.name)
+		if !ok {
+			models.Error(ctx, "Error parsing "+param.name)
+			return
+		}
+		*param.value = parsed
+	}
+	if err := h.svc.Validate(query); err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "export",
+		"event":    query.Event,
+		"format":   query.Format,
+		"contract": ctx.Query("contractAddress"),
+		"address":  ctx.Query("address"),
+	}).Info("export request")
+
+	contentType := "text/csv; charset=utf-8"
+	if query.Format == service.ExportJSONL {
+		contentType = "application/x-ndjson"
+	}
+	ctx.Header("Content-Type", contentType)
+	ctx.Header("Content-Disposition", `attachment; filename="`+query.Event+"."+query.Format+`"`)
+	ctx.Status(http.StatusOK)
+	// 已开始写出, 出错时只能记录日志并中断
+	count, err := h.svc.Export(ctx.Request.Context(), ctx.Writer, query)
+	if err != nil {
+		logger.WithModule("api").WithError(err).WithField("rows", count).Error("export failed")
+	}
+}
@@ -0,0 +1,81 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type HolderHandle struct {
+	svc service.HolderService
+}
+
+func NewHolderHandle(svc service.HolderService) *HolderHandle {
+	return &HolderHandle{svc: svc}
+}
+
+// Top 持有人按余额倒序
+func (h *HolderHandle) Top(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	pageNum, pageSize := parsePage(ctx)
+	list, total, err := h.svc.Top(ctx.Request.Context(), contractAddress, pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list holders failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
+
+func (h *HolderHandle) Count(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	total, err := h.svc.Count(ctx.Request.Context(), contractAddress)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("count holders failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, gin.H{"contract": contractAddress.Hex(), "holderCount": total})
+}
+
+// Balance 按已索引 Transfer 计算的余额, blockNumber 可选
+func (h *HolderHandle) Balance(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	account := common.HexToAddress(ctx.Query("account"))
+	var blockNumber *uint64
+	if value := ctx.Query("blockNumber"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			models.Error(ctx, "Error parsing blockNumber")
+			return
+		}
+		blockNumber = &parsed
+	}
+	balance, err := h.svc.BalanceAt(ctx.Request.Context(), contractAddress, account, blockNumber)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("holder balance failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, balance)
+}
+
+// Rebuild 按已索引的 Transfer 明细重算余额账本
+func (h *HolderHandle) Rebuild(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.PostForm("contractAddress"))
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "rebuild_holders",
+		"contract": contractAddress.Hex(),
+	}).Info("rebuild holders request")
+	if err := h.svc.Rebuild(ctx.Request.Context(), contractAddress); err != nil {
+		logger.WithModule("api").WithError(err).Error("rebuild holders failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx)
+}
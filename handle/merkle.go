@@ -0,0 +1,110 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type MerkleHandle struct {
+	svc service.MerkleService
+}
+
+func NewMerkleHandle(svc service.MerkleService) *MerkleHandle {
+	return &MerkleHandle{svc: svc}
+}
+
+// List 已生成的快照, contractAddress 可选
+func (m *MerkleHandle) List(ctx *gin.Context) {
+	contractAddress, ok := optionalAddress(ctx, "contractAddress")
+	if !ok {
+		models.Error(ctx, "Error parsing contractAddress")
+		return
+	}
+	pageNum, pageSize := parsePage(ctx)
+	list, total, err := m.svc.List(ctx.Request.Context(), contractAddress, pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list merkle snapshots failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
+
+// Leaves 快照叶子, 按 leafIndex 顺序
+func (m *MerkleHandle) Leaves(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Query("snapshotId"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing snapshotId")
+		return
+	}
+	pageNum, pageSize := parsePage(ctx)
+	list, total, err := m.svc.Leaves(ctx.Request.Context(), uint(id), pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list merkle leaves failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
+
+func (m *MerkleHandle) Proof(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.Query("snapshotId"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing snapshotId")
+		return
+	}
+	if !common.IsHexAddress(ctx.Query("account")) {
+		models.Error(ctx, "Error parsing account")
+		return
+	}
+	account := common.HexToAddress(ctx.Query("account"))
+	proof, err := m.svc.Proof(ctx.Request.Context(), uint(id), account)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("merkle proof failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, proof)
+}
+
+// Generate 生成快照, blockNumber 缺省为已索引的最新区块, 缺省与链上核对
+func (m *MerkleHandle) Generate(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.PostForm("contractAddress"))
+	var blockNumber *uint64
+	if value := ctx.PostForm("blockNumber"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			models.Error(ctx, "Error parsing blockNumber")
+			return
+		}
+		blockNumber = &parsed
+	}
+	verify := true
+	if value := ctx.PostForm("verify"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			models.Error(ctx, "Error parsing verify")
+			return
+		}
+		verify = parsed
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":      "merkle_snapshot",
+		"contract":    contractAddress.Hex(),
+		"blockNumber": ctx.PostForm("blockNumber"),
+		"verify":      verify,
+	}).Info("merkle snapshot request")
+	snapshot, err := m.svc.Generate(ctx.Request.Context(), contractAddress, blockNumber, verify)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("merkle snapshot failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, snapshot)
+}
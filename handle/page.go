@@ -0,0 +1,58 @@
+package handle
+
+import (
+	"go-solidity-staking/service"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+const maxPageSize = 200
+
+// parsePage 解析分页参数 pageNum / pageSize
+func parsePage(ctx *gin.Context) (int, int) {
+	pageNum, err := strconv.Atoi(ctx.DefaultQuery("pageNum", "1"))
+	if err != nil || pageNum < 1 {
+		pageNum = 1
+	}
+	pageSize, err := strconv.Atoi(ctx.DefaultQuery("pageSize", "20"))
+	if err != nil || pageSize < 1 {
+		pageSize = 20
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return pageNum, pageSize
+}
+
+// parseBlockRef 解析只读查询的 blockNumber / blockTag 参数
+func parseBlockRef(ctx *gin.Context) (service.BlockRef, error) {
+	return service.ParseBlockRef(ctx.Query("blockNumber"), ctx.Query("blockTag"))
+}
+
+// optionalAddress 可选的地址参数, 为空时返回 nil, 格式错误时 ok 为 false
+func optionalAddress(ctx *gin.Context, name string) (*common.Address, bool) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	if !common.IsHexAddress(value) {
+		return nil, false
+	}
+	address := common.HexToAddress(value)
+	return &address, true
+}
+
+// optionalUint64 可选的十进制整数参数, 为空时返回 nil, 格式错误时 ok 为 false
+func optionalUint64(ctx *gin.Context, name string) (*uint64, bool) {
+	value := ctx.Query(name)
+	if value == "" {
+		return nil, true
+	}
+	parsed, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return nil, false
+	}
+	return &parsed, true
+}
@@ -0,0 +1,60 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type PositionHandle struct {
+	svc service.PositionService
+}
+
+func NewPositionHandle(svc service.PositionService) *PositionHandle {
+	return &PositionHandle{svc: svc}
+}
+
+func (p *PositionHandle) List(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	sortBy := ctx.DefaultQuery("sortBy", "currentStaked")
+	order := ctx.DefaultQuery("order", "desc")
+	pageNum, pageSize := parsePage(ctx)
+	list, total, err := p.svc.List(ctx.Request.Context(), contractAddress, sortBy, order, pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list positions failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
+
+func (p *PositionHandle) Detail(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	account := common.HexToAddress(ctx.Query("account"))
+	position, err := p.svc.Get(ctx.Request.Context(), contractAddress, account)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("get position failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, position)
+}
+
+// Rebuild 按已索引的明细重算仓位
+func (p *PositionHandle) Rebuild(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.PostForm("contractAddress"))
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "rebuild_positions",
+		"contract": contractAddress.Hex(),
+	}).Info("rebuild positions request")
+	if err := p.svc.Rebuild(ctx.Request.Context(), contractAddress); err != nil {
+		logger.WithModule("api").WithError(err).Error("rebuild positions failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx)
+}
@@ -0,0 +1,84 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ReconcilerHandle struct {
+	svc service.ReconcilerService
+}
+
+func NewReconcilerHandle(svc service.ReconcilerService) *ReconcilerHandle {
+	return &ReconcilerHandle{svc: svc}
+}
+
+// List 对账差异, contractAddress 与 status 可选
+func (r *ReconcilerHandle) List(ctx *gin.Context) {
+	contractAddress, ok := optionalAddress(ctx, "contractAddress")
+	if !ok {
+		models.Error(ctx, "Error parsing contractAddress")
+		return
+	}
+	status := ctx.DefaultQuery("status", models.DiscrepancyOpen)
+	pageNum, pageSize := parsePage(ctx)
+	list, total, err := r.svc.List(ctx.Request.Context(), contractAddress, status, pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list discrepancies failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
+
+// Run 立即对账一个合约
+func (r *ReconcilerHandle) Run(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.PostForm("contractAddress"))
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "reconcile",
+		"contract": contractAddress.Hex(),
+	}).Info("reconcile request")
+	report, err := r.svc.Run(ctx.Request.Context(), contractAddress)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("reconcile failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, report)
+}
+
+// Reindex 重新索引差异所在合约的区块区间, fromBlock 可选
+func (r *ReconcilerHandle) Reindex(ctx *gin.Context) {
+	id, err := strconv.ParseUint(ctx.PostForm("id"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing id")
+		return
+	}
+	var fromBlock *uint64
+	if value := ctx.PostForm("fromBlock"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			models.Error(ctx, "Error parsing fromBlock")
+			return
+		}
+		fromBlock = &parsed
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":    "reindex_discrepancy",
+		"id":        id,
+		"fromBlock": ctx.PostForm("fromBlock"),
+	}).Info("reindex discrepancy request")
+	discrepancy, err := r.svc.Reindex(ctx.Request.Context(), uint(id), fromBlock)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("reindex discrepancy failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, discrepancy)
+}
@@ -0,0 +1,142 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type RegistryHandle struct {
+	svc  service.RegistryService
+	abis service.AbiStore
+}
+
+func NewRegistryHandle(svc service.RegistryService, abis service.AbiStore) *RegistryHandle {
+	return &RegistryHandle{svc: svc, abis: abis}
+}
+
+func (r *RegistryHandle) List(ctx *gin.Context) {
+	list, err := r.svc.List(ctx.Request.Context())
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list contracts failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, list)
+}
+
+// ListAbis 可用于 kind=abi 注册的 ABI 名称
+func (r *RegistryHandle) ListAbis(ctx *gin.Context) {
+	names, err := r.abis.List()
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("list abis failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, names)
+}
+
+func (r *RegistryHandle) Register(ctx *gin.Context) {
+	startBlock, err := strconv.ParseUint(ctx.DefaultPostForm("startBlock", "0"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing startBlock")
+		return
+	}
+	confirmations, err := strconv.ParseUint(ctx.DefaultPostForm("confirmations", "1"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing confirmations")
+		return
+	}
+	contract := models.WatchedContract{
+		Kind:          ctx.PostForm("kind"),
+		Address:       ctx.PostForm("address"),
+		Abi:           ctx.PostForm("abi"),
+		StartBlock:    startBlock,
+		Confirmations: confirmations,
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "register_contract",
+		"kind":     contract.Kind,
+		"abi":      contract.Abi,
+		"contract": contract.Address,
+	}).Info("register contract request")
+	value, err := r.svc.Register(ctx.Request.Context(), contract)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("register contract failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, value)
+}
+
+func (r *RegistryHandle) SetEnabled(ctx *gin.Context) {
+	address := common.HexToAddress(ctx.PostForm("address"))
+	enabled, err := strconv.ParseBool(ctx.PostForm("enabled"))
+	if err != nil {
+		models.Error(ctx, "Error parsing enabled")
+		return
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "enable_contract",
+		"contract": address.Hex(),
+		"enabled":  enabled,
+	}).Info("enable contract request")
+	if err := r.svc.SetEnabled(ctx.Request.Context(), address, enabled); err != nil {
+		logger.WithModule("api").WithError(err).Error("enable contract failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx)
+}
+
+func (r *RegistryHandle) Remove(ctx *gin.Context) {
+	address := common.HexToAddress(ctx.PostForm("address"))
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "remove_contract",
+		"contract": address.Hex(),
+	}).Info("remove contract request")
+	if err := r.svc.Remove(ctx.Request.Context(), address); err != nil {
+		logger.WithModule("api").WithError(err).Error("remove contract failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx)
+}
+
+// Reindex 重新索引合约的历史区间, 不影响实时检查点
+func (r *RegistryHandle) Reindex(ctx *gin.Context) {
+	address := common.HexToAddress(ctx.PostForm("address"))
+	from, err := strconv.ParseUint(ctx.PostForm("fromBlock"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing fromBlock")
+		return
+	}
+	to, err := strconv.ParseUint(ctx.PostForm("toBlock"), 10, 64)
+	if err != nil {
+		models.Error(ctx, "Error parsing toBlock")
+		return
+	}
+	purge, err := strconv.ParseBool(ctx.DefaultPostForm("purge", "false"))
+	if err != nil {
+		models.Error(ctx, "Error parsing purge")
+		return
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "reindex_contract",
+		"contract": address.Hex(),
+		"from":     from,
+		"to":       to,
+		"purge":    purge,
+	}).Info("reindex contract request")
+	if err := r.svc.Reindex(ctx.Request.Context(), address, from, to, purge); err != nil {
+		logger.WithModule("api").WithError(err).Error("reindex contract failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx)
+}
@@ -0,0 +1,63 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+)
+
+type RewardAccrualHandle struct {
+	svc service.RewardAccrualService
+}
+
+func NewRewardAccrualHandle(svc service.RewardAccrualService) *RewardAccrualHandle {
+	return &RewardAccrualHandle{svc: svc}
+}
+
+// EarnedAt 按已索引事件计算指定时间的 earned, timestamp 缺省为当前时间
+func (r *RewardAccrualHandle) EarnedAt(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	account := common.HexToAddress(ctx.Query("account"))
+	timestamp := uint64(time.Now().Unix())
+	if value := ctx.Query("timestamp"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			models.Error(ctx, "Error parsing timestamp")
+			return
+		}
+		timestamp = parsed
+	}
+	result, err := r.svc.EarnedAt(ctx.Request.Context(), contractAddress, account, timestamp)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("earned at failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, result)
+}
+
+// RateHistory 速率变更时间线与各区间发放的奖励, timestamp 缺省为当前时间
+func (r *RewardAccrualHandle) RateHistory(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	timestamp := uint64(time.Now().Unix())
+	if value := ctx.Query("timestamp"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			models.Error(ctx, "Error parsing timestamp")
+			return
+		}
+		timestamp = parsed
+	}
+	result, err := r.svc.RateHistory(ctx.Request.Context(), contractAddress, timestamp)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("reward rate history failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, result)
+}
@@ -0,0 +1,74 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type RollupHandle struct {
+	svc service.RollupService
+}
+
+func NewRollupHandle(svc service.RollupService) *RollupHandle {
+	return &RollupHandle{svc: svc}
+}
+
+// Series 时间序列, from/to 为 unix 秒, 缺省为最近 48 小时 (hour) 或 30 天 (day)
+func (r *RollupHandle) Series(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	granularity := ctx.DefaultQuery("granularity", service.GranularityDay)
+	to := uint64(time.Now().Unix())
+	if value := ctx.Query("to"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			models.Error(ctx, "Error parsing to")
+			return
+		}
+		to = parsed
+	}
+	span := uint64(30 * 86400)
+	if granularity == service.GranularityHour {
+		span = 48 * 3600
+	}
+	from := uint64(0)
+	if to > span {
+		from = to - span
+	}
+	if value := ctx.Query("from"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			models.Error(ctx, "Error parsing from")
+			return
+		}
+		from = parsed
+	}
+	series, err := r.svc.Series(ctx.Request.Context(), contractAddress, granularity, from, to)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("rollup series failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, series)
+}
+
+// Rebuild 从头重建合约的时间序列
+func (r *RollupHandle) Rebuild(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.PostForm("contractAddress"))
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "rebuild_rollup",
+		"contract": contractAddress.Hex(),
+	}).Info("rebuild rollup request")
+	if err := r.svc.Rebuild(ctx.Request.Context(), contractAddress); err != nil {
+		logger.WithModule("api").WithError(err).Error("rebuild rollup failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx)
+}
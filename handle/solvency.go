@@ -0,0 +1,48 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type SolvencyHandle struct {
+	svc service.SolvencyService
+}
+
+func NewSolvencyHandle(svc service.SolvencyService) *SolvencyHandle {
+	return &SolvencyHandle{svc: svc}
+}
+
+// Status 最新区块上的奖励池余额、负债与剩余可发放时间
+func (s *SolvencyHandle) Status(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "solvency",
+		"contract": contractAddress.Hex(),
+	}).Info("solvency request")
+	snapshot, err := s.svc.Status(ctx.Request.Context(), contractAddress)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("solvency failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, snapshot)
+}
+
+// History 后台任务写入的快照, 按时间倒序
+func (s *SolvencyHandle) History(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	pageNum, pageSize := parsePage(ctx)
+	list, total, err := s.svc.History(ctx.Request.Context(), contractAddress, pageNum, pageSize)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("solvency history failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.PageSuccess(ctx, "success", list, pageNum, pageSize, total)
+}
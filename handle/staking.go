@@ -130,12 +130,18 @@ func (s *StakingHandle) UpdateRewardRate(ctx *gin.Context) {
 func (s *StakingHandle) Earned(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
 	account := common.HexToAddress(ctx.Query("account"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "earned",
 		"contract": contractAddress.Hex(),
 		"account":  account.Hex(),
+		"block":    block.String(),
 	}).Info("earned request")
-	earned, err := s.svc.Earned(ctx.Request.Context(), contractAddress, account)
+	earned, err := s.svc.Earned(ctx.Request.Context(), contractAddress, account, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("earned failed")
 		models.Error(ctx, err.Error())
@@ -147,12 +153,18 @@ func (s *StakingHandle) Earned(ctx *gin.Context) {
 func (s *StakingHandle) StakedBalance(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
 	account := common.HexToAddress(ctx.Query("account"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "staked_balance",
 		"contract": contractAddress.Hex(),
 		"account":  account.Hex(),
+		"block":    block.String(),
 	}).Info("staked balance request")
-	balance, err := s.svc.StakedBalance(ctx.Request.Context(), contractAddress, account)
+	balance, err := s.svc.StakedBalance(ctx.Request.Context(), contractAddress, account, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("staked balance failed")
 		models.Error(ctx, err.Error())
@@ -163,11 +175,17 @@ func (s *StakingHandle) StakedBalance(ctx *gin.Context) {
 
 func (s *StakingHandle) RewardPerToken(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "reward_per_token",
 		"contract": contractAddress.Hex(),
+		"block":    block.String(),
 	}).Info("reward per token request")
-	value, err := s.svc.RewardPerToken(ctx.Request.Context(), contractAddress)
+	value, err := s.svc.RewardPerToken(ctx.Request.Context(), contractAddress, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("reward per token failed")
 		models.Error(ctx, err.Error())
@@ -178,11 +196,17 @@ func (s *StakingHandle) RewardPerToken(ctx *gin.Context) {
 
 func (s *StakingHandle) RewardPerTokenStored(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "reward_per_token_stored",
 		"contract": contractAddress.Hex(),
+		"block":    block.String(),
 	}).Info("reward per token stored request")
-	value, err := s.svc.RewardPerTokenStored(ctx.Request.Context(), contractAddress)
+	value, err := s.svc.RewardPerTokenStored(ctx.Request.Context(), contractAddress, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("reward_per_token_stored call failed")
 		models.Error(ctx, err.Error())
@@ -193,11 +217,17 @@ func (s *StakingHandle) RewardPerTokenStored(ctx *gin.Context) {
 
 func (s *StakingHandle) RewardRate(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "reward_rate",
 		"contract": contractAddress.Hex(),
+		"block":    block.String(),
 	}).Info("reward rate request")
-	value, err := s.svc.RewardRate(ctx.Request.Context(), contractAddress)
+	value, err := s.svc.RewardRate(ctx.Request.Context(), contractAddress, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("reward rate failed")
 		models.Error(ctx, err.Error())
@@ -208,11 +238,17 @@ func (s *StakingHandle) RewardRate(ctx *gin.Context) {
 
 func (s *StakingHandle) LastUpdateTime(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "last_update_time",
 		"contract": contractAddress.Hex(),
+		"block":    block.String(),
 	}).Info("last update time request")
-	value, err := s.svc.LastUpdateTime(ctx.Request.Context(), contractAddress)
+	value, err := s.svc.LastUpdateTime(ctx.Request.Context(), contractAddress, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("last update time failed")
 		models.Error(ctx, err.Error())
@@ -224,12 +260,18 @@ func (s *StakingHandle) LastUpdateTime(ctx *gin.Context) {
 func (s *StakingHandle) UserRewardPerTokenPaid(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
 	account := common.HexToAddress(ctx.Query("account"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "user_reward_per_token_paid",
 		"contract": contractAddress.Hex(),
 		"account":  account.Hex(),
+		"block":    block.String(),
 	}).Info("user reward per token paid request")
-	value, err := s.svc.UserRewardPerTokenPaid(ctx.Request.Context(), contractAddress, account)
+	value, err := s.svc.UserRewardPerTokenPaid(ctx.Request.Context(), contractAddress, account, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("user reward per token paid failed")
 		models.Error(ctx, err.Error())
@@ -241,12 +283,18 @@ func (s *StakingHandle) UserRewardPerTokenPaid(ctx *gin.Context) {
 func (s *StakingHandle) Rewards(ctx *gin.Context) {
 	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
 	account := common.HexToAddress(ctx.Query("account"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
 	logger.WithModule("api").WithFields(logrus.Fields{
 		"action":   "rewards",
 		"contract": contractAddress.Hex(),
 		"account":  account.Hex(),
+		"block":    block.String(),
 	}).Info("rewards request")
-	value, err := s.svc.Rewards(ctx.Request.Context(), contractAddress, account)
+	value, err := s.svc.Rewards(ctx.Request.Context(), contractAddress, account, block)
 	if err != nil {
 		logger.WithModule("api").WithError(err).Error("rewards failed")
 		models.Error(ctx, err.Error())
@@ -255,6 +303,27 @@ func (s *StakingHandle) Rewards(ctx *gin.Context) {
 	models.Success(ctx, value)
 }
 
+func (s *StakingHandle) OwnershipHistory(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	block, err := parseBlockRef(ctx)
+	if err != nil {
+		models.Error(ctx, err.Error())
+		return
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "ownership_history",
+		"contract": contractAddress.Hex(),
+		"block":    block.String(),
+	}).Info("ownership history request")
+	value, err := s.svc.OwnershipHistory(ctx.Request.Context(), contractAddress, block)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("ownership history failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, value)
+}
+
 func parsePrivateKey(hexKey string) (*ecdsa.PrivateKey, error) {
 	if len(hexKey) >= 2 && hexKey[:2] == "0x" {
 		hexKey = hexKey[2:]
@@ -0,0 +1,46 @@
+package handle
+
+import (
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"go-solidity-staking/service"
+	"strconv"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type YieldHandle struct {
+	svc service.YieldService
+}
+
+func NewYieldHandle(svc service.YieldService) *YieldHandle {
+	return &YieldHandle{svc: svc}
+}
+
+// Yield 当前与过去 window 秒的 APR/APY, window 缺省为 7 天
+func (y *YieldHandle) Yield(ctx *gin.Context) {
+	contractAddress := common.HexToAddress(ctx.Query("contractAddress"))
+	window := uint64(7 * 86400)
+	if value := ctx.Query("window"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			models.Error(ctx, "Error parsing window")
+			return
+		}
+		window = parsed
+	}
+	logger.WithModule("api").WithFields(logrus.Fields{
+		"action":   "yield",
+		"contract": contractAddress.Hex(),
+		"window":   window,
+	}).Info("yield request")
+	result, err := y.svc.Yield(ctx.Request.Context(), contractAddress, window)
+	if err != nil {
+		logger.WithModule("api").WithError(err).Error("yield failed")
+		models.Error(ctx, err.Error())
+		return
+	}
+	models.Success(ctx, result)
+}
@@ -0,0 +1,49 @@
+// Package testchain 测试共用的模拟链: 预置余额的测试账户与交易签名
+package testchain
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+)
+
+// 测试账户私钥 (go-ethereum 测试中公开使用的密钥, 不要用于真实网络)
+var (
+	OwnerKey, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	OtherKey, _ = crypto.HexToECDSA("7ee346e3f7efc685250053bfbafbfc880d58dc6145247053d4fb3cb0f66dfcb2")
+)
+
+// Ether 1e18
+var Ether = new(big.Int).Exp(big.NewInt(10), big.NewInt(18), nil)
+
+// NewBackend 启动模拟链, 每个账户预置 100 ETH, 测试结束时关闭
+func NewBackend(t *testing.T, keys ...*ecdsa.PrivateKey) *simulated.Backend {
+	t.Helper()
+	alloc := make(types.GenesisAlloc, len(keys))
+	for _, key := range keys {
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = types.Account{Balance: new(big.Int).Mul(big.NewInt(100), Ether)}
+	}
+	sim := simulated.NewBackend(alloc)
+	t.Cleanup(func() { sim.Close() })
+	return sim
+}
+
+// Transactor 按模拟链的 chainID 签名交易
+func Transactor(t *testing.T, ctx context.Context, client simulated.Client, key *ecdsa.PrivateKey) *bind.TransactOpts {
+	t.Helper()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		t.Fatalf("chain id: %v", err)
+	}
+	auth, err := bind.NewKeyedTransactorWithChainID(key, chainID)
+	if err != nil {
+		t.Fatalf("transactor: %v", err)
+	}
+	return auth
+}
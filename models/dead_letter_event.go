@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+const (
+	DeadLetterPending   = "pending"
+	DeadLetterResolved  = "resolved"
+	DeadLetterDiscarded = "discarded"
+)
+
+// DeadLetterEvent 入库失败的原始日志
+type DeadLetterEvent struct {
+	ID          uint      `json:"id"`
+	Kind        string    `json:"kind"`
+	Contract    string    `json:"contract"`
+	TxHash      string    `json:"txHash"`
+	LogIndex    uint      `json:"logIndex"`
+	BlockNumber uint64    `json:"blockNumber"`
+	BlockHash   string    `json:"blockHash"`
+	Topics      string    `json:"topics"`
+	Data        string    `json:"data"`
+	Error       string    `json:"error"`
+	Attempts    uint      `json:"attempts"`
+	Status      string    `json:"status"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+func (DeadLetterEvent) TableName() string {
+	return "dead_letter_event"
+}
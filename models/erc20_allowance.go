@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// ERC20Allowance 由 Approval 与 transferFrom 产生的 Transfer 推算的当前授权额度, 金额为最小单位
+type ERC20Allowance struct {
+	ID            uint      `json:"id"`
+	Contract      string    `json:"contract"`
+	Owner         string    `json:"owner"`
+	Spender       string    `json:"spender"`
+	Allowance     string    `json:"allowance"`
+	ApprovedValue string    `json:"approvedValue"` // 最近一次 Approval 的额度
+	Unlimited     bool      `json:"unlimited"`     // 额度 >= 2^255, 视为无限授权
+	ApprovalBlock uint64    `json:"approvalBlock"`
+	ApprovalTime  uint64    `json:"approvalTime"`
+	SpendBlock    uint64    `json:"spendBlock"` // 最近一次 transferFrom 使用额度的区块, 0 表示未使用
+	SpendTime     uint64    `json:"spendTime"`
+	LastBlock     uint64    `json:"-"` // 最近一次生效事件的位置, 用于忽略乱序到达的旧事件
+	LastLogIndex  uint      `json:"-"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func (ERC20Allowance) TableName() string {
+	return "erc20_allowance"
+}
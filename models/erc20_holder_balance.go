@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// ERC20HolderBalance 由 Transfer 事件累计的持有人余额, 金额为最小单位
+type ERC20HolderBalance struct {
+	ID         uint      `json:"id"`
+	Contract   string    `json:"contract"`
+	Holder     string    `json:"holder"`
+	Balance    string    `json:"balance"`
+	FirstBlock uint64    `json:"firstBlock"`
+	LastBlock  uint64    `json:"lastBlock"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+func (ERC20HolderBalance) TableName() string {
+	return "erc20_holder_balance"
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// MerkleSnapshot 指定区块所有质押用户 stakedBalance 的 Merkle 树, 叶子见 MerkleSnapshotLeaf
+type MerkleSnapshot struct {
+	ID          uint      `json:"id"`
+	Contract    string    `json:"contract"`
+	BlockNumber uint64    `json:"blockNumber"`
+	BlockTime   uint64    `json:"blockTime"`
+	Root        string    `json:"root"`
+	LeafCount   int       `json:"leafCount"`
+	TotalStaked string    `json:"totalStaked"`
+	Verified    bool      `json:"verified"` // 已逐个与该区块的链上 stakedBalance 核对
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+func (MerkleSnapshot) TableName() string {
+	return "merkle_snapshot"
+}
+
+// MerkleSnapshotLeaf 叶子按哈希升序编号, leafHash = keccak256(keccak256(abi.encode(account, amount)))
+type MerkleSnapshotLeaf struct {
+	ID         uint   `json:"-"`
+	SnapshotID uint   `json:"snapshotId"`
+	LeafIndex  int    `json:"leafIndex"`
+	Account    string `json:"account"`
+	Amount     string `json:"amount"`
+	LeafHash   string `json:"leafHash"`
+}
+
+func (MerkleSnapshotLeaf) TableName() string {
+	return "merkle_snapshot_leaf"
+}
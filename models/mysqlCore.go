@@ -14,7 +14,8 @@ import (
 var DB *gorm.DB
 var err error
 
-func init() {
+// Init 连接数据库, 由入口程序显式调用, 单元测试导入 models 时不需要数据库
+func Init() {
 	path := os.Getenv("APP_CONFIG_PATH")
 	if path == "" {
 		path = "./config/staking.ini"
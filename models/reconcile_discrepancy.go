@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+const (
+	DiscrepancyOpen      = "open"
+	DiscrepancyReindexed = "reindexed"
+	DiscrepancyResolved  = "resolved"
+)
+
+const (
+	ReconcileFieldStakedBalance = "stakedBalance"
+	ReconcileFieldRewards       = "rewards"
+	ReconcileFieldBalanceOf     = "balanceOf"
+	// 投影表与链上调用的对比
+	ReconcileFieldPositionStaked = "position.currentStaked"
+	ReconcileFieldHolderBalance  = "holder.balance"
+)
+
+// ReconcileDiscrepancy 同一区块下已索引状态与链上调用结果不一致的记录
+type ReconcileDiscrepancy struct {
+	ID           uint      `json:"id"`
+	Kind         string    `json:"kind"`
+	Contract     string    `json:"contract"`
+	Account      string    `json:"account"`
+	Field        string    `json:"field"`
+	BlockNumber  uint64    `json:"blockNumber"`
+	IndexedValue string    `json:"indexedValue"`
+	OnchainValue string    `json:"onchainValue"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+func (ReconcileDiscrepancy) TableName() string {
+	return "reconcile_discrepancy"
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	SolvencyOK       = "ok"
+	SolvencyWarning  = "warning"
+	SolvencyCritical = "critical"
+)
+
+// RewardPoolSnapshot 奖励池余额、未领取奖励与剩余可发放时间, 金额为最小单位
+type RewardPoolSnapshot struct {
+	ID            uint      `json:"id"`
+	Contract      string    `json:"contract"`
+	BlockNumber   uint64    `json:"blockNumber"`
+	BlockTime     uint64    `json:"blockTime"`
+	RewardToken   string    `json:"rewardToken"`
+	RewardBalance string    `json:"rewardBalance"` // 合约持有的奖励代币, 与质押代币相同时已扣除质押本金
+	Liabilities   string    `json:"liabilities"`   // 全部用户 earned 之和
+	Available     string    `json:"available"`     // rewardBalance - liabilities, 可为负
+	RewardRate    string    `json:"rewardRate"`
+	TotalStaked   string    `json:"totalStaked"`
+	RunwaySeconds *uint64   `json:"runwaySeconds"` // 按当前 rewardRate 还能发放的秒数, 没有质押或速率为 0 时为 null
+	Level         string    `json:"level"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+func (RewardPoolSnapshot) TableName() string {
+	return "reward_pool_snapshot"
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+type StakingEventOwnershipTransferred struct {
+	ID             uint
+	TxHash         string
+	LogIndex       uint
+	BlockNumber    uint64
+	BlockTimestamp uint64
+	Contract       string
+	PreviousOwner  string
+	NewOwner       string
+	CreatedAt      time.Time
+}
+
+func (StakingEventOwnershipTransferred) TableName() string {
+	return "staking_event_ownership_transferred"
+}
@@ -3,13 +3,14 @@ package models
 import "time"
 
 type StakingEventRewardRateUpdated struct {
-	ID            uint
-	TxHash        string
-	LogIndex      uint
-	BlockNumber   uint64
-	Contract      string
-	NewRewardRate string
-	CreatedAt     time.Time
+	ID             uint
+	TxHash         string
+	LogIndex       uint
+	BlockNumber    uint64
+	BlockTimestamp uint64
+	Contract       string
+	NewRewardRate  string
+	CreatedAt      time.Time
 }
 
 func (StakingEventRewardRateUpdated) TableName() string {
@@ -3,14 +3,15 @@ package models
 import "time"
 
 type StakingEventRewardsClaimed struct {
-	ID          uint
-	TxHash      string
-	LogIndex    uint
-	BlockNumber uint64
-	Contract    string
-	User        string
-	Amount      string
-	CreatedAt   time.Time
+	ID             uint
+	TxHash         string
+	LogIndex       uint
+	BlockNumber    uint64
+	BlockTimestamp uint64
+	Contract       string
+	User           string
+	Amount         string
+	CreatedAt      time.Time
 }
 
 func (StakingEventRewardsClaimed) TableName() string {
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// StakingPosition 由 Staked/Withdrawn/RewardsClaimed 事件累计的用户质押仓位, 金额为最小单位
+type StakingPosition struct {
+	ID             uint      `json:"id"`
+	Contract       string    `json:"contract"`
+	User           string    `json:"user"`
+	CurrentStaked  string    `json:"currentStaked"`
+	TotalStaked    string    `json:"totalStaked"`
+	TotalWithdrawn string    `json:"totalWithdrawn"`
+	TotalClaimed   string    `json:"totalClaimed"`
+	FirstBlock     uint64    `json:"firstBlock"`
+	LastBlock      uint64    `json:"lastBlock"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
+func (StakingPosition) TableName() string {
+	return "staking_position"
+}
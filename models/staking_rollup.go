@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// StakingRollup 质押活动按小时/天聚合的时间桶, 金额为最小单位
+type StakingRollup struct {
+	ID              uint      `json:"-"`
+	Contract        string    `json:"contract"`
+	Granularity     string    `json:"granularity"`
+	BucketStart     uint64    `json:"bucketStart"`
+	StakedAmount    string    `json:"stakedAmount"`
+	WithdrawnAmount string    `json:"withdrawnAmount"`
+	NetFlow         string    `json:"netFlow"`
+	ClaimedAmount   string    `json:"claimedAmount"`
+	Tvl             string    `json:"tvl"` // 桶结束时仍在质押的数量
+	StakeCount      uint      `json:"stakeCount"`
+	WithdrawCount   uint      `json:"withdrawCount"`
+	ClaimCount      uint      `json:"claimCount"`
+	UniqueStakers   uint      `json:"uniqueStakers"` // 桶内有质押 (Staked) 的不同地址数, 只提取或领取的地址不计入
+	UpdatedAt       time.Time `json:"updatedAt"`
+}
+
+func (StakingRollup) TableName() string {
+	return "staking_rollup"
+}
+
+// StakingRollupState 记录需要重建的最早时间, 为空表示时间桶已是最新
+type StakingRollupState struct {
+	ID        uint
+	Contract  string
+	DirtyFrom *uint64
+	Version   uint64
+	UpdatedAt time.Time
+}
+
+func (StakingRollupState) TableName() string {
+	return "staking_rollup_state"
+}
@@ -0,0 +1,12 @@
+package models
+
+type SyncBlock struct {
+	ID          uint
+	Name        string
+	BlockNumber uint64
+	BlockHash   string
+}
+
+func (SyncBlock) TableName() string {
+	return "sync_block"
+}
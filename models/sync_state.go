@@ -4,6 +4,7 @@ type SyncState struct {
 	ID          uint
 	Name        string
 	BlockNumber uint64
+	BlockHash   string
 }
 
 func (SyncState) TableName() string {
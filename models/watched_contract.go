@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// WatchedContract 索引器监听的合约
+type WatchedContract struct {
+	ID            uint      `json:"id"`
+	Kind          string    `json:"kind"`
+	Address       string    `json:"address"`
+	Abi           string    `json:"abi"` // kind=abi 时使用的 ABI 名称
+	StartBlock    uint64    `json:"startBlock"`
+	Confirmations uint64    `json:"confirmations"`
+	Enabled       bool      `json:"enabled"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+func (WatchedContract) TableName() string {
+	return "watched_contract"
+}
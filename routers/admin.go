@@ -0,0 +1,30 @@
+package routers
+
+import (
+	"go-solidity-staking/handle"
+
+	"github.com/gin-gonic/gin"
+)
+
+func AdminRoutersInit(r *gin.Engine, deadLetterHandle *handle.DeadLetterHandle, registryHandle *handle.RegistryHandle, positionHandle *handle.PositionHandle, rollupHandle *handle.RollupHandle, reconcilerHandle *handle.ReconcilerHandle, holderHandle *handle.HolderHandle, allowanceHandle *handle.AllowanceHandle, merkleHandle *handle.MerkleHandle) {
+	group := r.Group("/api/admin")
+	{
+		group.GET("/contracts", registryHandle.List)
+		group.POST("/contracts", registryHandle.Register)
+		group.POST("/contracts/enable", registryHandle.SetEnabled)
+		group.POST("/contracts/remove", registryHandle.Remove)
+		group.GET("/abis", registryHandle.ListAbis)
+		group.POST("/contracts/reindex", registryHandle.Reindex)
+		group.POST("/positions/rebuild", positionHandle.Rebuild)
+		group.POST("/series/rebuild", rollupHandle.Rebuild)
+		group.POST("/holders/rebuild", holderHandle.Rebuild)
+		group.POST("/allowances/rebuild", allowanceHandle.Rebuild)
+		group.GET("/discrepancies", reconcilerHandle.List)
+		group.POST("/discrepancies/reindex", reconcilerHandle.Reindex)
+		group.POST("/reconcile", reconcilerHandle.Run)
+		group.POST("/merkle/snapshots", merkleHandle.Generate)
+		group.GET("/deadLetters", deadLetterHandle.List)
+		group.POST("/deadLetters/retry", deadLetterHandle.Retry)
+		group.POST("/deadLetters/discard", deadLetterHandle.Discard)
+	}
+}
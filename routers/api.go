@@ -6,7 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-func ApiRoutersInit(r *gin.Engine, handle *handle.StakingHandle, tokenHandle *handle.ERC20TokenHandle) {
+func ApiRoutersInit(r *gin.Engine, handle *handle.StakingHandle, tokenHandle *handle.ERC20TokenHandle, positionHandle *handle.PositionHandle, accrualHandle *handle.RewardAccrualHandle, rollupHandle *handle.RollupHandle, yieldHandle *handle.YieldHandle, solvencyHandle *handle.SolvencyHandle, holderHandle *handle.HolderHandle, allowanceHandle *handle.AllowanceHandle, activityHandle *handle.ActivityHandle, exportHandle *handle.ExportHandle, merkleHandle *handle.MerkleHandle) {
 	group := r.Group("/api")
 	{
 		group.POST("/stake", handle.Stake)
@@ -21,9 +21,28 @@ func ApiRoutersInit(r *gin.Engine, handle *handle.StakingHandle, tokenHandle *ha
 		group.GET("/lastUpdateTime", handle.LastUpdateTime)
 		group.GET("/userRewardPerTokenPaid", handle.UserRewardPerTokenPaid)
 		group.GET("/rewards", handle.Rewards)
+		group.GET("/ownershipHistory", handle.OwnershipHistory)
+		group.GET("/positions", positionHandle.List)
+		group.GET("/position", positionHandle.Detail)
+		group.GET("/earnedAt", accrualHandle.EarnedAt)
+		group.GET("/rewardRateHistory", accrualHandle.RateHistory)
+		group.GET("/series", rollupHandle.Series)
+		group.GET("/yield", yieldHandle.Yield)
+		group.GET("/solvency", solvencyHandle.Status)
+		group.GET("/solvency/history", solvencyHandle.History)
 		group.POST("/approve", tokenHandle.Approve)
 		group.POST("/transfer", tokenHandle.Transfer)
 		group.GET("/balanceOf", tokenHandle.BalanceOf)
 		group.GET("/allowance", tokenHandle.Allowance)
+		group.GET("/holders", holderHandle.Top)
+		group.GET("/holderCount", holderHandle.Count)
+		group.GET("/holderBalance", holderHandle.Balance)
+		group.GET("/allowances", allowanceHandle.ListByOwner)
+		group.GET("/allowances/risky", allowanceHandle.Risky)
+		group.GET("/activity", activityHandle.Timeline)
+		group.GET("/export", exportHandle.Export)
+		group.GET("/merkle/snapshots", merkleHandle.List)
+		group.GET("/merkle/leaves", merkleHandle.Leaves)
+		group.GET("/merkle/proof", merkleHandle.Proof)
 	}
 }
@@ -3,13 +3,16 @@ CREATE TABLE IF NOT EXISTS staking_event_staked (
   tx_hash VARCHAR(66) NOT NULL COMMENT '交易哈希',
   log_index BIGINT UNSIGNED NOT NULL COMMENT '日志索引',
   block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)',
   contract VARCHAR(42) NOT NULL COMMENT '合约地址',
   user VARCHAR(42) NOT NULL COMMENT '用户地址',
   amount VARCHAR(78) NOT NULL COMMENT '质押数量(最小单位)',
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   PRIMARY KEY (id),
   UNIQUE KEY uniq_tx_log (tx_hash, log_index),
-  KEY idx_contract_block (contract, block_number)
+  KEY idx_contract_block (contract, block_number),
+  KEY idx_contract_time (contract, block_timestamp),
+  KEY idx_user_block (user, block_number, log_index)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: Staked 事件明细';
 
 CREATE TABLE IF NOT EXISTS staking_event_withdrawn (
@@ -17,13 +20,16 @@ CREATE TABLE IF NOT EXISTS staking_event_withdrawn (
   tx_hash VARCHAR(66) NOT NULL COMMENT '交易哈希',
   log_index BIGINT UNSIGNED NOT NULL COMMENT '日志索引',
   block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)',
   contract VARCHAR(42) NOT NULL COMMENT '合约地址',
   user VARCHAR(42) NOT NULL COMMENT '用户地址',
   amount VARCHAR(78) NOT NULL COMMENT '提现数量(最小单位)',
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   PRIMARY KEY (id),
   UNIQUE KEY uniq_tx_log (tx_hash, log_index),
-  KEY idx_contract_block (contract, block_number)
+  KEY idx_contract_block (contract, block_number),
+  KEY idx_contract_time (contract, block_timestamp),
+  KEY idx_user_block (user, block_number, log_index)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: Withdrawn 事件明细';
 
 CREATE TABLE IF NOT EXISTS staking_event_rewards_claimed (
@@ -31,13 +37,16 @@ CREATE TABLE IF NOT EXISTS staking_event_rewards_claimed (
   tx_hash VARCHAR(66) NOT NULL COMMENT '交易哈希',
   log_index BIGINT UNSIGNED NOT NULL COMMENT '日志索引',
   block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)',
   contract VARCHAR(42) NOT NULL COMMENT '合约地址',
   user VARCHAR(42) NOT NULL COMMENT '用户地址',
   amount VARCHAR(78) NOT NULL COMMENT '领取奖励(最小单位)',
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   PRIMARY KEY (id),
   UNIQUE KEY uniq_tx_log (tx_hash, log_index),
-  KEY idx_contract_block (contract, block_number)
+  KEY idx_contract_block (contract, block_number),
+  KEY idx_contract_time (contract, block_timestamp),
+  KEY idx_user_block (user, block_number, log_index)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: RewardsClaimed 事件明细';
 
 CREATE TABLE IF NOT EXISTS staking_event_reward_rate_updated (
@@ -45,19 +54,38 @@ CREATE TABLE IF NOT EXISTS staking_event_reward_rate_updated (
   tx_hash VARCHAR(66) NOT NULL COMMENT '交易哈希',
   log_index BIGINT UNSIGNED NOT NULL COMMENT '日志索引',
   block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)',
   contract VARCHAR(42) NOT NULL COMMENT '合约地址',
   new_reward_rate VARCHAR(78) NOT NULL COMMENT '新奖励速率',
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   PRIMARY KEY (id),
   UNIQUE KEY uniq_tx_log (tx_hash, log_index),
-  KEY idx_contract_block (contract, block_number)
+  KEY idx_contract_block (contract, block_number),
+  KEY idx_contract_time (contract, block_timestamp)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: RewardRateUpdated 事件明细';
 
+CREATE TABLE IF NOT EXISTS staking_event_ownership_transferred (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  tx_hash VARCHAR(66) NOT NULL COMMENT '交易哈希',
+  log_index BIGINT UNSIGNED NOT NULL COMMENT '日志索引',
+  block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)',
+  contract VARCHAR(42) NOT NULL COMMENT '合约地址',
+  previous_owner VARCHAR(42) NOT NULL COMMENT '原 owner',
+  new_owner VARCHAR(42) NOT NULL COMMENT '新 owner',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_tx_log (tx_hash, log_index),
+  KEY idx_contract_block (contract, block_number),
+  KEY idx_contract_time (contract, block_timestamp)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: OwnershipTransferred 事件明细';
+
 CREATE TABLE IF NOT EXISTS erc20_event_transfer (
   id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
   tx_hash VARCHAR(66) NOT NULL COMMENT '交易哈希',
   log_index BIGINT UNSIGNED NOT NULL COMMENT '日志索引',
   block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)',
   contract VARCHAR(42) NOT NULL COMMENT '合约地址',
   `from` VARCHAR(42) NOT NULL COMMENT '转出地址',
   `to` VARCHAR(42) NOT NULL COMMENT '转入地址',
@@ -65,7 +93,10 @@ CREATE TABLE IF NOT EXISTS erc20_event_transfer (
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   PRIMARY KEY (id),
   UNIQUE KEY uniq_tx_log (tx_hash, log_index),
-  KEY idx_contract_block (contract, block_number)
+  KEY idx_contract_block (contract, block_number),
+  KEY idx_contract_time (contract, block_timestamp),
+  KEY idx_from_block (`from`, block_number, log_index),
+  KEY idx_to_block (`to`, block_number, log_index)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='ERC20: Transfer 事件明细';
 
 CREATE TABLE IF NOT EXISTS erc20_event_approval (
@@ -73,6 +104,7 @@ CREATE TABLE IF NOT EXISTS erc20_event_approval (
   tx_hash VARCHAR(66) NOT NULL COMMENT '交易哈希',
   log_index BIGINT UNSIGNED NOT NULL COMMENT '日志索引',
   block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)',
   contract VARCHAR(42) NOT NULL COMMENT '合约地址',
   owner VARCHAR(42) NOT NULL COMMENT '授权人',
   spender VARCHAR(42) NOT NULL COMMENT '被授权人',
@@ -80,5 +112,200 @@ CREATE TABLE IF NOT EXISTS erc20_event_approval (
   created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
   PRIMARY KEY (id),
   UNIQUE KEY uniq_tx_log (tx_hash, log_index),
-  KEY idx_contract_block (contract, block_number)
+  KEY idx_contract_block (contract, block_number),
+  KEY idx_contract_time (contract, block_timestamp),
+  KEY idx_owner_block (owner, block_number, log_index),
+  KEY idx_spender_block (spender, block_number, log_index)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='ERC20: Approval 事件明细';
+
+CREATE TABLE IF NOT EXISTS sync_block (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  name VARCHAR(128) NOT NULL COMMENT '同步键',
+  block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_hash VARCHAR(66) NOT NULL COMMENT '区块哈希',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_name_block (name, block_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='已处理区块哈希(用于重组检测)';
+
+CREATE TABLE IF NOT EXISTS sync_state (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  name VARCHAR(128) NOT NULL COMMENT '同步键',
+  block_number BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '检查点区块高度',
+  block_hash VARCHAR(66) NOT NULL DEFAULT '' COMMENT '检查点区块哈希',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_name (name)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='同步检查点';
+
+CREATE TABLE IF NOT EXISTS dead_letter_event (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  kind VARCHAR(32) NOT NULL COMMENT '合约类型',
+  contract VARCHAR(42) NOT NULL COMMENT '合约地址',
+  tx_hash VARCHAR(66) NOT NULL COMMENT '交易哈希',
+  log_index BIGINT UNSIGNED NOT NULL COMMENT '日志索引',
+  block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_hash VARCHAR(66) NOT NULL COMMENT '区块哈希',
+  topics TEXT NOT NULL COMMENT 'topics(JSON 数组)',
+  data MEDIUMTEXT NOT NULL COMMENT 'data(hex)',
+  error TEXT NOT NULL COMMENT '最近一次错误',
+  attempts INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '尝试次数',
+  status VARCHAR(16) NOT NULL DEFAULT 'pending' COMMENT 'pending/resolved/discarded',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_tx_log (tx_hash, log_index),
+  KEY idx_status (status, attempts)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='入库失败的事件(死信)';
+
+CREATE TABLE IF NOT EXISTS watched_contract (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  kind VARCHAR(32) NOT NULL COMMENT '合约类型 staking/erc20/abi',
+  address VARCHAR(42) NOT NULL COMMENT '合约地址',
+  abi VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'kind=abi 时使用的 ABI 名称',
+  start_block BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '起始区块',
+  confirmations BIGINT UNSIGNED NOT NULL DEFAULT 1 COMMENT '确认数',
+  enabled TINYINT(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_address (address)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='索引器监听的合约';
+
+CREATE TABLE IF NOT EXISTS staking_position (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  contract VARCHAR(42) NOT NULL COMMENT '合约地址',
+  user VARCHAR(42) NOT NULL COMMENT '用户地址',
+  current_staked DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '当前质押(最小单位)',
+  total_staked DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '累计质押',
+  total_withdrawn DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '累计提取',
+  total_claimed DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '累计领取奖励',
+  first_block BIGINT UNSIGNED NOT NULL COMMENT '首次活动区块',
+  last_block BIGINT UNSIGNED NOT NULL COMMENT '最近活动区块',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_contract_user (contract, user),
+  KEY idx_contract_current (contract, current_staked)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: 用户质押仓位投影';
+
+CREATE TABLE IF NOT EXISTS staking_rollup (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  contract VARCHAR(42) NOT NULL COMMENT '合约地址',
+  granularity VARCHAR(8) NOT NULL COMMENT '粒度 hour/day',
+  bucket_start BIGINT UNSIGNED NOT NULL COMMENT '桶开始时间(unix 秒, UTC 对齐)',
+  staked_amount DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '质押数量',
+  withdrawn_amount DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '提取数量',
+  net_flow DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '净流入 = 质押 - 提取',
+  claimed_amount DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '领取奖励数量',
+  tvl DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '桶结束时的质押总量',
+  stake_count INT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Staked 次数',
+  withdraw_count INT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'Withdrawn 次数',
+  claim_count INT UNSIGNED NOT NULL DEFAULT 0 COMMENT 'RewardsClaimed 次数',
+  unique_stakers INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '桶内有质押的不同地址数',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_contract_bucket (contract, granularity, bucket_start)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: 活动时间序列聚合';
+
+CREATE TABLE IF NOT EXISTS staking_rollup_state (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  contract VARCHAR(42) NOT NULL COMMENT '合约地址',
+  dirty_from BIGINT UNSIGNED NULL COMMENT '需要重建的最早区块时间, NULL 表示已是最新',
+  version BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '每次标记递增, 重建期间有新事件时不清除标记',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_contract (contract)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: 时间序列重建标记';
+
+CREATE TABLE IF NOT EXISTS reconcile_discrepancy (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  kind VARCHAR(32) NOT NULL COMMENT '合约类型 staking/erc20',
+  contract VARCHAR(42) NOT NULL COMMENT '合约地址',
+  account VARCHAR(42) NOT NULL COMMENT '账户地址',
+  field VARCHAR(32) NOT NULL COMMENT '对账字段 stakedBalance/rewards/balanceOf/position.currentStaked/holder.balance',
+  block_number BIGINT UNSIGNED NOT NULL COMMENT '对账区块, open 记录为最近一次不一致的区块',
+  indexed_value DECIMAL(65,0) NOT NULL COMMENT '按已索引事件计算的值',
+  onchain_value DECIMAL(65,0) NOT NULL COMMENT '链上调用返回的值',
+  status VARCHAR(16) NOT NULL DEFAULT 'open' COMMENT '状态 open/reindexed/resolved',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_check (contract, account, field, block_number),
+  KEY idx_status (status)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='链上状态与索引状态对账差异';
+
+CREATE TABLE IF NOT EXISTS reward_pool_snapshot (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  contract VARCHAR(42) NOT NULL COMMENT '质押合约地址',
+  block_number BIGINT UNSIGNED NOT NULL COMMENT '区块高度',
+  block_time BIGINT UNSIGNED NOT NULL COMMENT '区块时间',
+  reward_token VARCHAR(42) NOT NULL COMMENT '奖励代币地址',
+  reward_balance DECIMAL(65,0) NOT NULL COMMENT '合约持有的奖励代币(已扣除同币种质押本金)',
+  liabilities DECIMAL(65,0) NOT NULL COMMENT '全部用户未领取奖励之和',
+  available DECIMAL(65,0) NOT NULL COMMENT '余额 - 负债, 可为负',
+  reward_rate DECIMAL(65,0) NOT NULL COMMENT '每秒奖励',
+  total_staked DECIMAL(65,0) NOT NULL COMMENT '质押总量',
+  runway_seconds BIGINT UNSIGNED NULL COMMENT '按当前速率剩余可发放秒数',
+  level VARCHAR(16) NOT NULL COMMENT '告警级别 ok/warning/critical',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (id),
+  KEY idx_contract_block (contract, block_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: 奖励池偿付能力快照';
+
+CREATE TABLE IF NOT EXISTS erc20_holder_balance (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  contract VARCHAR(42) NOT NULL COMMENT '代币合约地址',
+  holder VARCHAR(42) NOT NULL COMMENT '持有人地址',
+  balance DECIMAL(65,0) NOT NULL DEFAULT 0 COMMENT '余额(最小单位)',
+  first_block BIGINT UNSIGNED NOT NULL COMMENT '首次转账区块',
+  last_block BIGINT UNSIGNED NOT NULL COMMENT '最近转账区块',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_contract_holder (contract, holder),
+  KEY idx_contract_balance (contract, balance)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='ERC20: 持有人余额账本';
+
+CREATE TABLE IF NOT EXISTS erc20_allowance (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  contract VARCHAR(42) NOT NULL COMMENT '代币合约地址',
+  owner VARCHAR(42) NOT NULL COMMENT '授权人',
+  spender VARCHAR(42) NOT NULL COMMENT '被授权人',
+  allowance VARCHAR(78) NOT NULL COMMENT '当前额度(最小单位)',
+  approved_value VARCHAR(78) NOT NULL COMMENT '最近一次 Approval 的额度',
+  unlimited TINYINT(1) NOT NULL DEFAULT 0 COMMENT '额度 >= 2^255 视为无限授权',
+  approval_block BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '最近一次 Approval 区块',
+  approval_time BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '最近一次 Approval 区块时间',
+  spend_block BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '最近一次 transferFrom 使用额度的区块',
+  spend_time BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '最近一次 transferFrom 使用额度的区块时间',
+  last_block BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '最近生效事件区块',
+  last_log_index BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '最近生效事件日志索引',
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP COMMENT '更新时间',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_contract_owner_spender (contract, owner, spender),
+  KEY idx_owner (owner),
+  KEY idx_spender (spender)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='ERC20: 当前授权额度账本';
+
+CREATE TABLE IF NOT EXISTS merkle_snapshot (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  contract VARCHAR(42) NOT NULL COMMENT 'staking 合约地址',
+  block_number BIGINT UNSIGNED NOT NULL COMMENT '快照区块',
+  block_time BIGINT UNSIGNED NOT NULL COMMENT '快照区块时间',
+  root VARCHAR(66) NOT NULL COMMENT 'Merkle 根',
+  leaf_count INT NOT NULL COMMENT '叶子数(质押余额大于 0 的用户数)',
+  total_staked VARCHAR(78) NOT NULL COMMENT '叶子金额合计(最小单位)',
+  verified TINYINT(1) NOT NULL DEFAULT 0 COMMENT '已与链上 stakedBalance 逐个核对',
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP COMMENT '创建时间',
+  PRIMARY KEY (id),
+  KEY idx_contract_block (contract, block_number)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: 质押余额 Merkle 快照';
+
+CREATE TABLE IF NOT EXISTS merkle_snapshot_leaf (
+  id BIGINT UNSIGNED NOT NULL AUTO_INCREMENT COMMENT '主键',
+  snapshot_id BIGINT UNSIGNED NOT NULL COMMENT '快照 ID',
+  leaf_index INT NOT NULL COMMENT '叶子序号(按哈希升序)',
+  account VARCHAR(42) NOT NULL COMMENT '用户地址',
+  amount VARCHAR(78) NOT NULL COMMENT '质押余额(最小单位)',
+  leaf_hash VARCHAR(66) NOT NULL COMMENT '叶子哈希',
+  PRIMARY KEY (id),
+  UNIQUE KEY uniq_snapshot_index (snapshot_id, leaf_index),
+  UNIQUE KEY uniq_snapshot_account (snapshot_id, account)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4 COMMENT='Staking: Merkle 快照叶子';
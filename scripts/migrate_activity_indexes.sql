@@ -0,0 +1,14 @@
+-- 已有库补充按地址查询活动的索引, 新库直接使用 create_event_detail_tables.sql
+ALTER TABLE staking_event_staked ADD KEY idx_user_block (user, block_number, log_index);
+
+ALTER TABLE staking_event_withdrawn ADD KEY idx_user_block (user, block_number, log_index);
+
+ALTER TABLE staking_event_rewards_claimed ADD KEY idx_user_block (user, block_number, log_index);
+
+ALTER TABLE erc20_event_transfer
+  ADD KEY idx_from_block (`from`, block_number, log_index),
+  ADD KEY idx_to_block (`to`, block_number, log_index);
+
+ALTER TABLE erc20_event_approval
+  ADD KEY idx_owner_block (owner, block_number, log_index),
+  ADD KEY idx_spender_block (spender, block_number, log_index);
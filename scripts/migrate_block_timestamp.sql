@@ -0,0 +1,30 @@
+-- 已有库补充区块时间字段, 新库直接使用 create_event_detail_tables.sql
+ALTER TABLE event_log ADD COLUMN block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)' AFTER block_number;
+
+ALTER TABLE staking_event_staked
+  ADD COLUMN block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)' AFTER block_number,
+  ADD KEY idx_contract_time (contract, block_timestamp);
+
+ALTER TABLE staking_event_withdrawn
+  ADD COLUMN block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)' AFTER block_number,
+  ADD KEY idx_contract_time (contract, block_timestamp);
+
+ALTER TABLE staking_event_rewards_claimed
+  ADD COLUMN block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)' AFTER block_number,
+  ADD KEY idx_contract_time (contract, block_timestamp);
+
+ALTER TABLE staking_event_reward_rate_updated
+  ADD COLUMN block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)' AFTER block_number,
+  ADD KEY idx_contract_time (contract, block_timestamp);
+
+ALTER TABLE staking_event_ownership_transferred
+  ADD COLUMN block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)' AFTER block_number,
+  ADD KEY idx_contract_time (contract, block_timestamp);
+
+ALTER TABLE erc20_event_transfer
+  ADD COLUMN block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)' AFTER block_number,
+  ADD KEY idx_contract_time (contract, block_timestamp);
+
+ALTER TABLE erc20_event_approval
+  ADD COLUMN block_timestamp BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '区块时间(unix 秒)' AFTER block_number,
+  ADD KEY idx_contract_time (contract, block_timestamp);
@@ -0,0 +1,6 @@
+-- 已有库合并重复的 open 差异: 同一账户同一字段只保留最新一条, 之后每轮对账原地刷新
+DELETE d FROM reconcile_discrepancy d
+  JOIN reconcile_discrepancy n
+    ON n.contract = d.contract AND n.account = d.account AND n.field = d.field
+   AND n.status = 'open' AND n.id > d.id
+ WHERE d.status = 'open';
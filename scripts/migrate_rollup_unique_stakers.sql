@@ -0,0 +1,2 @@
+-- 已有库的 unique_stakers 曾计入只提取或领取的地址, 标记全部时间序列从头重建
+UPDATE staking_rollup_state SET dirty_from = 0, version = version + 1;
@@ -0,0 +1,2 @@
+-- 已有库补充检查点区块哈希(用于重组检测), 新库直接使用 create_event_detail_tables.sql
+ALTER TABLE sync_state ADD COLUMN block_hash VARCHAR(66) NOT NULL DEFAULT '' COMMENT '检查点区块哈希';
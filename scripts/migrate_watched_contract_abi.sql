@@ -0,0 +1,2 @@
+-- 已有库补充 kind=abi 合约使用的 ABI 名称, 新库直接使用 create_event_detail_tables.sql
+ALTER TABLE watched_contract ADD COLUMN abi VARCHAR(64) NOT NULL DEFAULT '' COMMENT 'kind=abi 时使用的 ABI 名称' AFTER address;
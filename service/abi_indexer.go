@@ -0,0 +1,196 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"go-solidity-staking/models"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+)
+
+// AbiStore 按名称加载合约 ABI, 名称对应 abi 目录下的 <name>.abi 文件
+type AbiStore interface {
+	Load(name string) (*abi.ABI, error)
+	List() ([]string, error)
+}
+
+type fileAbiStore struct {
+	dir   string
+	mu    sync.Mutex
+	cache map[string]*abi.ABI
+}
+
+func NewFileAbiStore(dir string) AbiStore {
+	return &fileAbiStore{dir: dir, cache: make(map[string]*abi.ABI)}
+}
+
+func (f *fileAbiStore) Load(name string) (*abi.ABI, error) {
+	if name == "" || filepath.Base(name) != name {
+		return nil, fmt.Errorf("invalid abi name %q", name)
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if parsed, ok := f.cache[name]; ok {
+		return parsed, nil
+	}
+	file, err := os.Open(filepath.Join(f.dir, name+".abi"))
+	if err != nil {
+		return nil, fmt.Errorf("open abi %s: %w", name, err)
+	}
+	defer file.Close()
+	parsed, err := abi.JSON(file)
+	if err != nil {
+		return nil, fmt.Errorf("parse abi %s: %w", name, err)
+	}
+	f.cache[name] = &parsed
+	return &parsed, nil
+}
+
+func (f *fileAbiStore) List() ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(f.dir, "*.abi"))
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(matches))
+	for _, match := range matches {
+		names = append(names, strings.TrimSuffix(filepath.Base(match), ".abi"))
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// resolveABI 取得通用合约的 ABI; 死信重试等场景只有地址时从注册表查 ABI 名称
+func (l *listenerService) resolveABI(target WatchTarget) (*abi.ABI, error) {
+	name := target.AbiName
+	if name == "" {
+		var contract models.WatchedContract
+		if err := models.DB.Where("address = ?", target.Address.Hex()).First(&contract).Error; err != nil {
+			return nil, fmt.Errorf("lookup abi of %s: %w", target.Address.Hex(), err)
+		}
+		name = contract.Abi
+	}
+	return l.abis.Load(name)
+}
+
+// abiTopics ABI 中所有非匿名事件的签名
+func abiTopics(parsed *abi.ABI) []common.Hash {
+	topics := make([]common.Hash, 0, len(parsed.Events))
+	for _, ev := range parsed.Events {
+		if !ev.Anonymous {
+			topics = append(topics, ev.ID)
+		}
+	}
+	return topics
+}
+
+// decodeABILog 按 ABI 解析任意事件, 以参数名为键写入 event_log.event_args
+func (l *listenerService) decodeABILog(parsed *abi.ABI, logEntry types.Log) (logWriter, error) {
+	ev, err := parsed.EventByID(logEntry.Topics[0])
+	if err != nil {
+		return nil, nil
+	}
+	args, err := decodeEventArgs(ev, logEntry)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s: %w", ev.Name, err)
+	}
+	return func(tx *gorm.DB) error {
+		_, err := l.recordEventArgs(tx, logEntry, ev.Name, args)
+		return err
+	}, nil
+}
+
+func decodeEventArgs(ev *abi.Event, logEntry types.Log) (map[string]interface{}, error) {
+	values, err := ev.Inputs.UnpackValues(logEntry.Data)
+	if err != nil {
+		return nil, err
+	}
+	args := make(map[string]interface{}, len(ev.Inputs))
+	topic, value := 1, 0
+	for i, input := range ev.Inputs {
+		// 未命名的参数使用 arg<序号>
+		name := input.Name
+		if name == "" {
+			name = fmt.Sprintf("arg%d", i)
+		}
+		if !input.Indexed {
+			args[name] = abiValue(values[value])
+			value++
+			continue
+		}
+		if topic >= len(logEntry.Topics) {
+			return nil, errors.New("topic count mismatch")
+		}
+		// 动态类型的 indexed 参数只能还原为 keccak256 哈希
+		out := make(map[string]interface{}, 1)
+		if err := abi.ParseTopicsIntoMap(out, abi.Arguments{input}, logEntry.Topics[topic:topic+1]); err != nil {
+			return nil, err
+		}
+		args[name] = abiValue(out[input.Name])
+		topic++
+	}
+	return args, nil
+}
+
+// abiValue 把 ABI 解码结果转换为 JSON 友好的类型:
+// 整数超出 int64 精度的用字符串, 地址/哈希/bytes 用 hex, 数组与结构体递归转换
+func abiValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case nil:
+		return nil
+	case *big.Int:
+		return value.String()
+	case common.Address:
+		return value.Hex()
+	case common.Hash:
+		return value.Hex()
+	case []byte:
+		return hexutil.Encode(value)
+	case string, bool, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return value
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Array:
+		// bytesN
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			b := make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(b), rv)
+			return hexutil.Encode(b)
+		}
+		fallthrough
+	case reflect.Slice:
+		out := make([]interface{}, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			out[i] = abiValue(rv.Index(i).Interface())
+		}
+		return out
+	case reflect.Struct:
+		out := make(map[string]interface{}, rv.NumField())
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			name := field.Tag.Get("json")
+			if name == "" {
+				name = field.Name
+			}
+			out[name] = abiValue(rv.Field(i).Interface())
+		}
+		return out
+	case reflect.Ptr:
+		if rv.IsNil() {
+			return nil
+		}
+		return abiValue(rv.Elem().Interface())
+	}
+	return fmt.Sprint(v)
+}
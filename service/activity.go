@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ActivityStaked           = "staked"
+	ActivityWithdrawn        = "withdrawn"
+	ActivityRewardsClaimed   = "rewards_claimed"
+	ActivityTransferIn       = "transfer_in"
+	ActivityTransferOut      = "transfer_out"
+	ActivityApproval         = "approval"          // 该地址授权给他人
+	ActivityApprovalReceived = "approval_received" // 他人授权给该地址
+)
+
+type ActivityService interface {
+	Timeline(ctx context.Context, account common.Address, cursor string, limit int) (*ActivityPage, error)
+}
+
+// ActivityItem 一条账户活动, amount 为最小单位, amountFormatted 按代币 decimals 换算
+type ActivityItem struct {
+	Type            string `json:"type"`
+	Contract        string `json:"contract"`
+	Token           string `json:"token"`
+	Symbol          string `json:"symbol"`
+	Counterparty    string `json:"counterparty"` // 转账对手方 / 授权的另一方
+	Amount          string `json:"amount"`
+	AmountFormatted string `json:"amountFormatted"`
+	TxHash          string `json:"txHash"`
+	LogIndex        uint   `json:"logIndex"`
+	BlockNumber     uint64 `json:"blockNumber"`
+	BlockTime       uint64 `json:"blockTime"`
+}
+
+// ActivityPage 按 (区块, 日志索引) 倒序, nextCursor 为空表示没有更多
+type ActivityPage struct {
+	Items      []ActivityItem `json:"items"`
+	NextCursor string         `json:"nextCursor"`
+}
+
+type activityService struct {
+	tokens *tokenCache
+}
+
+func NewActivityService(client *ethclient.Client) ActivityService {
+	return &activityService{tokens: newTokenCache(client)}
+}
+
+// Timeline 合并质押与 ERC20 明细表中与该地址相关的事件
+func (a *activityService) Timeline(ctx context.Context, account common.Address, cursor string, limit int) (*ActivityPage, error) {
+	before := ""
+	var beforeArgs []interface{}
+	if cursor != "" {
+		block, index, err := parseActivityCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		before = " AND (block_number < ? OR (block_number = ? AND log_index < ?))"
+		beforeArgs = []interface{}{block, block, index}
+	}
+	address := account.Hex()
+	var args []interface{}
+	var parts []string
+	for _, source := range []struct {
+		table string
+		kind  string
+	}{
+		{"staking_event_staked", ActivityStaked},
+		{"staking_event_withdrawn", ActivityWithdrawn},
+		{"staking_event_rewards_claimed", ActivityRewardsClaimed},
+	} {
+		parts = append(parts, `SELECT '`+source.kind+`' AS type, contract, '' AS counterparty, amount, tx_hash, log_index, block_number, block_timestamp
+  FROM `+source.table+` WHERE user = ?`+before)
+		args = append(append(args, address), beforeArgs...)
+	}
+	parts = append(parts, `SELECT CASE WHEN `+"`to`"+` = ? THEN '`+ActivityTransferIn+`' ELSE '`+ActivityTransferOut+`' END, contract,
+    CASE WHEN `+"`to`"+` = ? THEN `+"`from`"+` ELSE `+"`to`"+` END, value, tx_hash, log_index, block_number, block_timestamp
+  FROM erc20_event_transfer WHERE (`+"`from`"+` = ? OR `+"`to`"+` = ?)`+before)
+	args = append(append(args, address, address, address, address), beforeArgs...)
+	parts = append(parts, `SELECT CASE WHEN owner = ? THEN '`+ActivityApproval+`' ELSE '`+ActivityApprovalReceived+`' END, contract,
+    CASE WHEN owner = ? THEN spender ELSE owner END, value, tx_hash, log_index, block_number, block_timestamp
+  FROM erc20_event_approval WHERE (owner = ? OR spender = ?)`+before)
+	args = append(append(args, address, address, address, address), beforeArgs...)
+
+	var rows []struct {
+		Type           string
+		Contract       string
+		Counterparty   string
+		Amount         string
+		TxHash         string
+		LogIndex       uint
+		BlockNumber    uint64
+		BlockTimestamp uint64
+	}
+	// 多取一条判断是否还有下一页
+	query := strings.Join(parts, "\nUNION ALL\n") + "\nORDER BY block_number DESC, log_index DESC LIMIT ?"
+	args = append(args, limit+1)
+	if err := models.DB.WithContext(ctx).Raw(query, args...).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("query activity: %w", err)
+	}
+	page := &ActivityPage{Items: make([]ActivityItem, 0, limit)}
+	if len(rows) > limit {
+		rows = rows[:limit]
+		last := rows[limit-1]
+		page.NextCursor = fmt.Sprintf("%d-%d", last.BlockNumber, last.LogIndex)
+	}
+	for _, row := range rows {
+		item := ActivityItem{
+			Type:         row.Type,
+			Contract:     row.Contract,
+			Counterparty: row.Counterparty,
+			Amount:       row.Amount,
+			TxHash:       row.TxHash,
+			LogIndex:     row.LogIndex,
+			BlockNumber:  row.BlockNumber,
+			BlockTime:    row.BlockTimestamp,
+		}
+		// 代币信息取不到时仍返回原始数量
+		token, err := a.tokens.resolve(ctx, common.HexToAddress(row.Contract), activityToken(row.Type))
+		if err != nil {
+			logger.WithModule("activity").WithError(err).WithFields(logrus.Fields{
+				"contract": row.Contract,
+				"type":     row.Type,
+			}).Warn("resolve token failed")
+		} else {
+			item.Token = token.address.Hex()
+			item.Symbol = token.symbol
+			item.AmountFormatted = formatUnits(row.Amount, token.decimals)
+		}
+		page.Items = append(page.Items, item)
+	}
+	return page, nil
+}
+
+// activityToken 质押/提取为质押代币, 领取为奖励代币, ERC20 事件为合约本身
+func activityToken(kind string) string {
+	switch kind {
+	case ActivityStaked, ActivityWithdrawn:
+		return tokenStaking
+	case ActivityRewardsClaimed:
+		return tokenReward
+	}
+	return tokenSelf
+}
+
+func parseActivityCursor(cursor string) (uint64, uint64, error) {
+	blockStr, indexStr, ok := strings.Cut(cursor, "-")
+	if ok {
+		block, err := strconv.ParseUint(blockStr, 10, 64)
+		if err == nil {
+			index, err := strconv.ParseUint(indexStr, 10, 64)
+			if err == nil {
+				return block, index, nil
+			}
+		}
+	}
+	return 0, 0, fmt.Errorf("invalid cursor %q", cursor)
+}
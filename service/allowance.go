@@ -0,0 +1,334 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-solidity-staking/models"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 额度达到 2^255 视为无限授权 (常见为 type(uint256).max)
+var unlimitedAllowance = new(big.Int).Lsh(big.NewInt(1), 255)
+
+type AllowanceService interface {
+	ListByOwner(ctx context.Context, contract *common.Address, owner common.Address, pageNum int, pageSize int) ([]models.ERC20Allowance, int64, error)
+	Risky(ctx context.Context, contract *common.Address, spender common.Address, staleAfter time.Duration, pageNum int, pageSize int) ([]RiskyApproval, int64, error)
+	Rebuild(ctx context.Context, contract common.Address) error
+}
+
+// RiskyApproval 无限授权或长期未使用的授权
+type RiskyApproval struct {
+	models.ERC20Allowance
+	Stale       bool   `json:"stale"`
+	IdleSeconds uint64 `json:"idleSeconds"` // 距最近一次授权或使用的秒数
+}
+
+type allowanceService struct{}
+
+func NewAllowanceService() AllowanceService {
+	return &allowanceService{}
+}
+
+// ListByOwner 授权人的全部非零授权, contract 为空时包含所有代币
+func (a *allowanceService) ListByOwner(ctx context.Context, contract *common.Address, owner common.Address, pageNum int, pageSize int) ([]models.ERC20Allowance, int64, error) {
+	query := models.DB.WithContext(ctx).Model(&models.ERC20Allowance{}).Where("owner = ? and allowance <> '0'", owner.Hex())
+	if contract != nil {
+		query = query.Where("contract = ?", contract.Hex())
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count allowances: %w", err)
+	}
+	var list []models.ERC20Allowance
+	err := query.Order("contract").Order("spender").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list allowances: %w", err)
+	}
+	return list, total, nil
+}
+
+// Risky 授予 spender 的非零授权中, 无限授权或超过 staleAfter 未授权也未使用的记录
+func (a *allowanceService) Risky(ctx context.Context, contract *common.Address, spender common.Address, staleAfter time.Duration, pageNum int, pageSize int) ([]RiskyApproval, int64, error) {
+	now := uint64(time.Now().Unix())
+	staleBefore := uint64(0)
+	if seconds := uint64(staleAfter.Seconds()); seconds < now {
+		staleBefore = now - seconds
+	}
+	query := models.DB.WithContext(ctx).Model(&models.ERC20Allowance{}).
+		Where("spender = ? and allowance <> '0'", spender.Hex()).
+		Where("unlimited = ? or GREATEST(approval_time, spend_time) < ?", true, staleBefore)
+	if contract != nil {
+		query = query.Where("contract = ?", contract.Hex())
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count risky approvals: %w", err)
+	}
+	var rows []models.ERC20Allowance
+	err := query.Order("GREATEST(approval_time, spend_time)").Order("id").
+		Offset((pageNum - 1) * pageSize).
+		Limit(pageSize).
+		Find(&rows).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list risky approvals: %w", err)
+	}
+	list := make([]RiskyApproval, 0, len(rows))
+	for _, row := range rows {
+		lastActive := row.ApprovalTime
+		if row.SpendTime > lastActive {
+			lastActive = row.SpendTime
+		}
+		item := RiskyApproval{ERC20Allowance: row, Stale: lastActive < staleBefore}
+		if now > lastActive {
+			item.IdleSeconds = now - lastActive
+		}
+		list = append(list, item)
+	}
+	return list, total, nil
+}
+
+// Rebuild 按 Approval/Transfer 明细重算代币的全部授权额度
+func (a *allowanceService) Rebuild(ctx context.Context, contract common.Address) error {
+	return models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return rebuildAllowances(tx, contract.Hex(), nil)
+	})
+}
+
+// allowanceEvent 额度账本重放所需的 Approval/Transfer 字段
+type allowanceEvent struct {
+	approval    bool
+	txHash      string
+	blockNumber uint64
+	logIndex    uint
+	timestamp   uint64
+	owner       string
+	spender     string // Approval 的被授权人; Transfer 为同笔 transferFrom 的使用方
+	value       *big.Int
+}
+
+// after 事件是否晚于账本记录的最近生效事件
+func (ev allowanceEvent) after(entry *models.ERC20Allowance) bool {
+	if entry.LastBlock != ev.blockNumber {
+		return ev.blockNumber > entry.LastBlock
+	}
+	return ev.logIndex > entry.LastLogIndex
+}
+
+// applyAllowanceEvent 把事件应用到账本记录; 旧事件返回 false.
+// Transfer 只记录使用时间: transferFrom 在 Transfer 前发出的 Approval 已更新了剩余额度
+func applyAllowanceEvent(entry *models.ERC20Allowance, ev allowanceEvent) bool {
+	if !ev.after(entry) {
+		return false
+	}
+	if ev.approval {
+		entry.Allowance = ev.value.String()
+		entry.ApprovedValue = ev.value.String()
+		entry.Unlimited = ev.value.Cmp(unlimitedAllowance) >= 0
+		entry.ApprovalBlock = ev.blockNumber
+		entry.ApprovalTime = ev.timestamp
+	} else {
+		entry.SpendBlock = ev.blockNumber
+		entry.SpendTime = ev.timestamp
+	}
+	entry.LastBlock = ev.blockNumber
+	entry.LastLogIndex = ev.logIndex
+	entry.UpdatedAt = time.Now()
+	return true
+}
+
+// applyApproval 新写入一条 Approval 明细后更新额度
+func applyApproval(tx *gorm.DB, logEntry types.Log, owner common.Address, spender common.Address, value *big.Int) error {
+	ev := allowanceEvent{
+		approval:    true,
+		txHash:      logEntry.TxHash.Hex(),
+		blockNumber: logEntry.BlockNumber,
+		logIndex:    logEntry.Index,
+		timestamp:   logEntry.BlockTimestamp,
+		owner:       owner.Hex(),
+		spender:     spender.Hex(),
+		value:       value,
+	}
+	return saveAllowanceEvent(tx, logEntry.Address.Hex(), ev)
+}
+
+// applyTransferSpend 新写入一条 Transfer 明细后记录 transferFrom 对额度的使用.
+// 日志中没有调用方, 只有同笔交易中紧邻的 Approval(owner=from) 才表示这是一次 transferFrom
+// (本仓库的 ERC20 在 transferFrom 中发出剩余额度), 其 spender 即使用方;
+// 普通 transfer 即使收款方已获授权也不涉及额度
+func applyTransferSpend(tx *gorm.DB, logEntry types.Log, from common.Address, to common.Address, value *big.Int) error {
+	if from.Hex() == zeroAddress || from == to || logEntry.Index == 0 {
+		return nil
+	}
+	var approvals []models.ERC20EventApproval
+	err := tx.Where("tx_hash = ? and log_index = ? and owner = ?", logEntry.TxHash.Hex(), logEntry.Index-1, from.Hex()).Limit(1).Find(&approvals).Error
+	if err != nil {
+		return err
+	}
+	if len(approvals) == 0 {
+		return nil
+	}
+	ev := allowanceEvent{
+		txHash:      logEntry.TxHash.Hex(),
+		blockNumber: logEntry.BlockNumber,
+		logIndex:    logEntry.Index,
+		timestamp:   logEntry.BlockTimestamp,
+		owner:       from.Hex(),
+		spender:     approvals[0].Spender,
+		value:       value,
+	}
+	return saveAllowanceEvent(tx, logEntry.Address.Hex(), ev)
+}
+
+// saveAllowanceEvent 加锁读取账本记录, 应用事件后写回; Transfer 没有对应授权记录时忽略
+func saveAllowanceEvent(tx *gorm.DB, contract string, ev allowanceEvent) error {
+	var entry models.ERC20Allowance
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("contract = ? and owner = ? and spender = ?", contract, ev.owner, ev.spender).
+		First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if !ev.approval {
+			return nil
+		}
+		entry = models.ERC20Allowance{Contract: contract, Owner: ev.owner, Spender: ev.spender, Allowance: "0", ApprovedValue: "0"}
+	} else if err != nil {
+		return err
+	}
+	if !applyAllowanceEvent(&entry, ev) {
+		return nil
+	}
+	return tx.Save(&entry).Error
+}
+
+// collectAllowanceAffected 删除 Approval/Transfer 明细前调用, 返回涉及的授权人
+func collectAllowanceAffected(tx *gorm.DB, contract string, where string, args ...interface{}) ([]string, error) {
+	owners := []string{}
+	seen := make(map[string]bool)
+	for _, item := range []struct {
+		model  interface{}
+		column string
+	}{
+		{&models.ERC20EventApproval{}, "owner"},
+		{&models.ERC20EventTransfer{}, "`from`"},
+	} {
+		var rows []string
+		err := tx.Model(item.model).Distinct(item.column).
+			Where("contract = ?", contract).Where(where, args...).
+			Pluck(item.column, &rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, owner := range rows {
+			if owner != zeroAddress && !seen[owner] {
+				seen[owner] = true
+				owners = append(owners, owner)
+			}
+		}
+	}
+	return owners, nil
+}
+
+// rebuildAllowances 按明细重放额度; owners 为 nil 时重算代币的全部授权人
+func rebuildAllowances(tx *gorm.DB, contract string, owners []string) error {
+	if owners != nil && len(owners) == 0 {
+		return nil
+	}
+	deleteQuery := tx.Where("contract = ?", contract)
+	approvalQuery := tx.Where("contract = ?", contract)
+	transferQuery := tx.Where("contract = ? and `from` <> ?", contract, zeroAddress)
+	if owners != nil {
+		deleteQuery = deleteQuery.Where("owner IN ?", owners)
+		approvalQuery = approvalQuery.Where("owner IN ?", owners)
+		transferQuery = transferQuery.Where("`from` IN ?", owners)
+	}
+	if err := deleteQuery.Delete(&models.ERC20Allowance{}).Error; err != nil {
+		return err
+	}
+	var approvals []models.ERC20EventApproval
+	if err := approvalQuery.Find(&approvals).Error; err != nil {
+		return err
+	}
+	var transfers []models.ERC20EventTransfer
+	if err := transferQuery.Find(&transfers).Error; err != nil {
+		return err
+	}
+	rows, err := replayAllowances(contract, approvals, transfers)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(rows, 500).Error
+}
+
+// replayAllowances 按 (区块, 日志索引) 顺序重放 Approval/Transfer 明细得到授权记录;
+// 只有紧邻同笔 Approval 的 Transfer 计为使用, 其余 Transfer 不影响额度
+func replayAllowances(contract string, approvals []models.ERC20EventApproval, transfers []models.ERC20EventTransfer) ([]models.ERC20Allowance, error) {
+	type logKey struct {
+		txHash   string
+		logIndex uint
+	}
+	approvalAt := make(map[logKey]models.ERC20EventApproval, len(approvals))
+	events := make([]allowanceEvent, 0, len(approvals)+len(transfers))
+	for _, row := range approvals {
+		value, ok := new(big.Int).SetString(row.Value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid approval value %q", row.Value)
+		}
+		approvalAt[logKey{row.TxHash, row.LogIndex}] = row
+		events = append(events, allowanceEvent{approval: true, txHash: row.TxHash, blockNumber: row.BlockNumber, logIndex: row.LogIndex, timestamp: row.BlockTimestamp, owner: row.Owner, spender: row.Spender, value: value})
+	}
+	for _, row := range transfers {
+		if row.From == row.To || row.LogIndex == 0 {
+			continue
+		}
+		prev, ok := approvalAt[logKey{row.TxHash, row.LogIndex - 1}]
+		if !ok || prev.Owner != row.From {
+			continue
+		}
+		value, ok := new(big.Int).SetString(row.Value, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid transfer value %q", row.Value)
+		}
+		events = append(events, allowanceEvent{txHash: row.TxHash, blockNumber: row.BlockNumber, logIndex: row.LogIndex, timestamp: row.BlockTimestamp, owner: row.From, spender: prev.Spender, value: value})
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].blockNumber != events[j].blockNumber {
+			return events[i].blockNumber < events[j].blockNumber
+		}
+		return events[i].logIndex < events[j].logIndex
+	})
+
+	type allowanceKey struct {
+		owner   string
+		spender string
+	}
+	book := make(map[allowanceKey]*models.ERC20Allowance)
+	var order []allowanceKey
+	for _, ev := range events {
+		key := allowanceKey{ev.owner, ev.spender}
+		entry, ok := book[key]
+		if !ok {
+			if !ev.approval {
+				continue
+			}
+			entry = &models.ERC20Allowance{Contract: contract, Owner: ev.owner, Spender: ev.spender, Allowance: "0", ApprovedValue: "0"}
+			book[key] = entry
+			order = append(order, key)
+		}
+		applyAllowanceEvent(entry, ev)
+	}
+	rows := make([]models.ERC20Allowance, 0, len(order))
+	for _, key := range order {
+		rows = append(rows, *book[key])
+	}
+	return rows, nil
+}
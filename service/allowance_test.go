@@ -0,0 +1,47 @@
+package service
+
+import (
+	"go-solidity-staking/models"
+	"testing"
+)
+
+func TestReplayAllowancesIgnoresPlainTransfer(t *testing.T) {
+	const (
+		contract = "0x00000000000000000000000000000000000000C0"
+		owner    = "0x00000000000000000000000000000000000000A1"
+		spender  = "0x00000000000000000000000000000000000000B2"
+		other    = "0x00000000000000000000000000000000000000D3"
+	)
+	approvals := []models.ERC20EventApproval{
+		// approve(spender, 100)
+		{TxHash: "0x01", LogIndex: 0, BlockNumber: 10, BlockTimestamp: 1000, Owner: owner, Spender: spender, Value: "100"},
+	}
+	transfers := []models.ERC20EventTransfer{
+		// transfer(spender, 30): 收款方已获授权, 但不是 transferFrom
+		{TxHash: "0x02", LogIndex: 0, BlockNumber: 11, BlockTimestamp: 1100, From: owner, To: spender, Value: "30"},
+	}
+	rows, err := replayAllowances(contract, approvals, transfers)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d allowance rows, want 1", len(rows))
+	}
+	if rows[0].Allowance != "100" || rows[0].SpendBlock != 0 {
+		t.Fatalf("allowance %s spent at block %d after plain transfer, want 100 unspent", rows[0].Allowance, rows[0].SpendBlock)
+	}
+
+	// transferFrom(owner, other, 40) 先发出剩余额度的 Approval, 再发出 Transfer
+	approvals = append(approvals, models.ERC20EventApproval{TxHash: "0x03", LogIndex: 0, BlockNumber: 12, BlockTimestamp: 1200, Owner: owner, Spender: spender, Value: "60"})
+	transfers = append(transfers, models.ERC20EventTransfer{TxHash: "0x03", LogIndex: 1, BlockNumber: 12, BlockTimestamp: 1200, From: owner, To: other, Value: "40"})
+	rows, err = replayAllowances(contract, approvals, transfers)
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("got %d allowance rows, want 1", len(rows))
+	}
+	if rows[0].Allowance != "60" || rows[0].SpendBlock != 12 || rows[0].SpendTime != 1200 {
+		t.Fatalf("allowance %s spent at block %d time %d, want 60 spent at block 12 time 1200", rows[0].Allowance, rows[0].SpendBlock, rows[0].SpendTime)
+	}
+}
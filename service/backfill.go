@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-solidity-staking/logger"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// blockRange 闭区间 [start, end]
+type blockRange struct {
+	start uint64
+	end   uint64
+}
+
+type fetchResult struct {
+	batch *rangeBatch
+	err   error
+}
+
+// replayParallel 追赶历史区块: worker 并发拉取、解析各区间, 提交严格按区块顺序进行,
+// 每个区间与其检查点同一事务提交, 检查点只会覆盖连续完成的区间;
+// 提交前确认区间首块的 parentHash 等于上一区间末块的哈希, 不相连时由调用方检测重组并回滚;
+// 某个区间失败时停止提交, 之前已提交的区间保留, 下次 tick 从检查点继续
+func (l *listenerService) replayParallel(ctx context.Context, key string, targets []WatchTarget, start uint64, end uint64, opts rangeOptions) error {
+	size := l.chunks.get(key)
+	var ranges []blockRange
+	for s := start; s <= end; s += size {
+		e := s + size - 1
+		if e > end || e < s {
+			e = end
+		}
+		ranges = append(ranges, blockRange{start: s, end: e})
+		if e == end {
+			break
+		}
+	}
+	concurrency := l.chunks.cfg.Concurrency
+	logger.WithModule("listener").WithFields(logrus.Fields{
+		"key":         key,
+		"start":       start,
+		"end":         end,
+		"chunks":      len(ranges),
+		"concurrency": concurrency,
+	}).Info("parallel backfill started")
+
+	ctx, cancel := context.WithCancel(ctx)
+	results := make([]chan fetchResult, len(ranges))
+	for i := range results {
+		results[i] = make(chan fetchResult, 1)
+	}
+	// 限制已拉取但未提交的区间数, 避免慢提交时结果堆积在内存
+	window := make(chan struct{}, concurrency*2)
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				batch, err := l.fetchRangeSplit(ctx, key, targets, ranges[i], opts)
+				results[i] <- fetchResult{batch: batch, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(jobs)
+		for i := range ranges {
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for i := range ranges {
+		var res fetchResult
+		select {
+		case res = <-results[i]:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if res.err != nil {
+			return res.err
+		}
+		if err := l.commitRange(res.batch, opts); err != nil {
+			return err
+		}
+		<-window
+	}
+	return nil
+}
+
+// fetchRangeSplit 节点报区间过大时对半拆分后分别拉取再合并, 并缩小后续区间
+func (l *listenerService) fetchRangeSplit(ctx context.Context, key string, targets []WatchTarget, r blockRange, opts rangeOptions) (*rangeBatch, error) {
+	batch, err := l.fetchRange(ctx, targets, r.start, r.end, opts)
+	if err == nil || !isRangeTooLarge(err) || r.start == r.end {
+		return batch, err
+	}
+	l.chunks.shrink(key)
+	mid := r.start + (r.end-r.start)/2
+	left, err := l.fetchRangeSplit(ctx, key, targets, blockRange{start: r.start, end: mid}, opts)
+	if err != nil {
+		return nil, err
+	}
+	right, err := l.fetchRangeSplit(ctx, key, targets, blockRange{start: mid + 1, end: r.end}, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.checkpoint && len(left.blocks) > 0 && right.parent != left.blocks[len(left.blocks)-1].hash {
+		return nil, fmt.Errorf("%w: block %d parent %s, block %d %s", errRangeUnlinked, right.start, right.parent.Hex(), left.end, left.blocks[len(left.blocks)-1].hash.Hex())
+	}
+	// 左半区间的末块总会记录, 不在哈希窗口内时去掉, 保持记录的区块连续
+	if len(left.blocks) > 0 && left.blocks[len(left.blocks)-1].number < opts.hashesFrom {
+		left.blocks = left.blocks[:len(left.blocks)-1]
+	}
+	left.logs = append(left.logs, right.logs...)
+	left.blocks = append(left.blocks, right.blocks...)
+	left.end = right.end
+	return left, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ErrStateUnavailable 节点已裁剪该区块的状态, 历史读取需要归档节点
+var ErrStateUnavailable = errors.New("historical state not available, an archive node is required")
+
+// BlockRef 只读调用使用的区块: 零值为 latest
+type BlockRef struct {
+	Number  *big.Int // 区块高度或 safe/finalized 对应的负数标签
+	Pending bool
+}
+
+// LatestBlock 读取最新区块状态
+var LatestBlock = BlockRef{}
+
+// ParseBlockRef 解析 blockNumber / blockTag, 两者都为空时返回 latest
+func ParseBlockRef(blockNumber string, blockTag string) (BlockRef, error) {
+	if blockNumber != "" && blockTag != "" {
+		return BlockRef{}, errors.New("blockNumber and blockTag are mutually exclusive")
+	}
+	if blockNumber != "" {
+		number, err := strconv.ParseUint(blockNumber, 10, 64)
+		if err != nil {
+			return BlockRef{}, fmt.Errorf("invalid blockNumber %q", blockNumber)
+		}
+		return BlockRef{Number: new(big.Int).SetUint64(number)}, nil
+	}
+	switch strings.ToLower(blockTag) {
+	case "", "latest":
+		return LatestBlock, nil
+	case "pending":
+		return BlockRef{Pending: true}, nil
+	case "safe":
+		return BlockRef{Number: big.NewInt(int64(rpc.SafeBlockNumber))}, nil
+	case "finalized":
+		return BlockRef{Number: big.NewInt(int64(rpc.FinalizedBlockNumber))}, nil
+	}
+	return BlockRef{}, fmt.Errorf("invalid blockTag %q, expected latest/safe/finalized/pending", blockTag)
+}
+
+// Height 指定了具体高度时返回该高度
+func (b BlockRef) Height() (uint64, bool) {
+	if b.Number == nil || b.Number.Sign() < 0 {
+		return 0, false
+	}
+	return b.Number.Uint64(), true
+}
+
+func (b BlockRef) String() string {
+	if b.Pending {
+		return "pending"
+	}
+	if b.Number == nil {
+		return "latest"
+	}
+	if b.Number.Sign() < 0 {
+		return rpc.BlockNumber(b.Number.Int64()).String()
+	}
+	return b.Number.String()
+}
+
+func (b BlockRef) callOpts(ctx context.Context) *bind.CallOpts {
+	return &bind.CallOpts{Context: ctx, BlockNumber: b.Number, Pending: b.Pending}
+}
+
+// callErr 把节点返回的状态缺失错误转换为 ErrStateUnavailable
+func (b BlockRef) callErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := b.Height(); !ok {
+		return err
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{
+		"missing trie node",
+		"historical state",
+		"state is not available",
+		"state not available",
+		"required historical state unavailable",
+		"pruned",
+	} {
+		if strings.Contains(msg, s) {
+			return fmt.Errorf("block %s: %w (%v)", b, ErrStateUnavailable, err)
+		}
+	}
+	if strings.Contains(msg, "header not found") || strings.Contains(msg, "unknown block") {
+		return fmt.Errorf("block %s not found: %w", b, err)
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"go-solidity-staking/logger"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// 区间耗时低于该值视为查询开销低, 下次放大区间
+const fastChunkDuration = 2 * time.Second
+
+// ChunkConfig 控制 eth_getLogs 的区块区间大小
+type ChunkConfig struct {
+	Initial uint64 // 初始区间
+	Min     uint64 // 节点报错时最多缩小到的区间
+	Max     uint64 // 查询较快时最多放大到的区间
+	// Concurrency 追赶历史区块时并发拉取的区间数, 1 为顺序回放
+	Concurrency int
+}
+
+// DefaultChunkConfig 未配置时的默认区间
+var DefaultChunkConfig = ChunkConfig{Initial: 2000, Min: 10, Max: 10000, Concurrency: 1}
+
+func (c ChunkConfig) normalize() ChunkConfig {
+	if c.Min == 0 {
+		c.Min = 1
+	}
+	if c.Max < c.Min {
+		c.Max = c.Min
+	}
+	if c.Initial < c.Min {
+		c.Initial = c.Min
+	}
+	if c.Initial > c.Max {
+		c.Initial = c.Max
+	}
+	if c.Concurrency < 1 {
+		c.Concurrency = 1
+	}
+	return c
+}
+
+// chunkSizer 按同步键记录当前的区间大小
+type chunkSizer struct {
+	cfg   ChunkConfig
+	mu    sync.Mutex
+	sizes map[string]uint64
+}
+
+func newChunkSizer(cfg ChunkConfig) *chunkSizer {
+	return &chunkSizer{cfg: cfg.normalize(), sizes: make(map[string]uint64)}
+}
+
+func (c *chunkSizer) get(key string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if size, ok := c.sizes[key]; ok {
+		return size
+	}
+	return c.cfg.Initial
+}
+
+// shrink 区间减半, 已到最小值时返回 false
+func (c *chunkSizer) shrink(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size, ok := c.sizes[key]
+	if !ok {
+		size = c.cfg.Initial
+	}
+	if size <= c.cfg.Min {
+		return false
+	}
+	size /= 2
+	if size < c.cfg.Min {
+		size = c.cfg.Min
+	}
+	c.sizes[key] = size
+	return true
+}
+
+func (c *chunkSizer) grow(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	size, ok := c.sizes[key]
+	if !ok {
+		size = c.cfg.Initial
+	}
+	size *= 2
+	if size > c.cfg.Max {
+		size = c.cfg.Max
+	}
+	c.sizes[key] = size
+}
+
+// replayChunked 把 [start, end] 拆成多个区间依次回放, 每个区间结束后由 replay 写入检查点
+func (l *listenerService) replayChunked(ctx context.Context, key string, start uint64, end uint64, replay func(start uint64, end uint64) error) error {
+	for start <= end {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		size := l.chunks.get(key)
+		chunkEnd := start + size - 1
+		if chunkEnd > end {
+			chunkEnd = end
+		}
+		began := time.Now()
+		if err := replay(start, chunkEnd); err != nil {
+			if isRangeTooLarge(err) && l.chunks.shrink(key) {
+				logger.WithModule("listener").WithError(err).WithFields(logrus.Fields{
+					"key":   key,
+					"start": start,
+					"end":   chunkEnd,
+					"size":  l.chunks.get(key),
+				}).Warn("log range too large, shrinking")
+				continue
+			}
+			return err
+		}
+		if time.Since(began) < fastChunkDuration {
+			l.chunks.grow(key)
+		}
+		start = chunkEnd + 1
+	}
+	return nil
+}
+
+// isRangeTooLarge 识别各节点服务商对 eth_getLogs 区间过大的报错,
+// 只匹配明确的区间/结果数超限, 其它错误缩小区间也无济于事;
+// 限流 (429 rate limit exceeded) 应等待重试, 不能当作区间过大
+func isRangeTooLarge(err error) bool {
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests") {
+		return false
+	}
+	for _, s := range []string{
+		"query returned more than",
+		"exceed maximum block range",
+		"range is too large",
+		"limit exceeded",
+	} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
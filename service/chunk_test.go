@@ -0,0 +1,31 @@
+package service
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestIsRangeTooLarge(t *testing.T) {
+	tests := []struct {
+		msg  string
+		want bool
+	}{
+		{"query returned more than 10000 results", true},
+		{"Query returned more than 10000 results. Try with this block range [0x1, 0x2]", true},
+		{"exceed maximum block range: 5000", true},
+		{"block range is too large", true},
+		{"eth_getLogs limit exceeded", true},
+		{"429 Too Many Requests: rate limit exceeded", false},
+		{"rate limit exceeded", false},
+		{"invalid block range params", false},
+		{"block range extends beyond current head block", false},
+		{"header not found", false},
+		{"context deadline exceeded", false},
+		{"connection reset by peer", false},
+	}
+	for _, tt := range tests {
+		if got := isRangeTooLarge(errors.New(tt.msg)); got != tt.want {
+			t.Errorf("isRangeTooLarge(%q) = %v, want %v", tt.msg, got, tt.want)
+		}
+	}
+}
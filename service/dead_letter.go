@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// 每轮重试处理的死信数量
+const deadLetterBatch = 100
+
+type DeadLetterService interface {
+	List(ctx context.Context, status string, pageNum int, pageSize int) ([]models.DeadLetterEvent, int64, error)
+	Retry(ctx context.Context, id uint) (*models.DeadLetterEvent, error)
+	Discard(ctx context.Context, id uint) error
+	StartRetryLoop(ctx context.Context, interval time.Duration)
+}
+
+type deadLetterService struct {
+	listener    ListenerService
+	maxAttempts uint
+}
+
+func NewDeadLetterService(listener ListenerService, maxAttempts uint) DeadLetterService {
+	return &deadLetterService{listener: listener, maxAttempts: maxAttempts}
+}
+
+func (d *deadLetterService) List(ctx context.Context, status string, pageNum int, pageSize int) ([]models.DeadLetterEvent, int64, error) {
+	query := models.DB.WithContext(ctx).Model(&models.DeadLetterEvent{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count dead letters: %w", err)
+	}
+	var list []models.DeadLetterEvent
+	err := query.Order("id desc").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list dead letters: %w", err)
+	}
+	return list, total, nil
+}
+
+func (d *deadLetterService) Retry(ctx context.Context, id uint) (*models.DeadLetterEvent, error) {
+	var entry models.DeadLetterEvent
+	if err := models.DB.WithContext(ctx).First(&entry, id).Error; err != nil {
+		return nil, fmt.Errorf("get dead letter %d: %w", id, err)
+	}
+	if entry.Status == models.DeadLetterResolved {
+		return &entry, nil
+	}
+	// 已丢弃的条目是人工确认不再处理的, 不允许再次入库
+	if entry.Status == models.DeadLetterDiscarded {
+		return &entry, fmt.Errorf("dead letter %d discarded", id)
+	}
+	if err := d.retry(ctx, &entry); err != nil {
+		return &entry, err
+	}
+	return &entry, nil
+}
+
+func (d *deadLetterService) Discard(ctx context.Context, id uint) error {
+	result := models.DB.WithContext(ctx).Model(&models.DeadLetterEvent{}).
+		Where("id = ? and status = ?", id, models.DeadLetterPending).
+		Update("status", models.DeadLetterDiscarded)
+	if result.Error != nil {
+		return fmt.Errorf("discard dead letter %d: %w", id, result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("dead letter %d not pending", id)
+	}
+	return nil
+}
+
+func (d *deadLetterService) StartRetryLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var list []models.DeadLetterEvent
+			err := models.DB.WithContext(ctx).
+				Where("status = ? and attempts < ?", models.DeadLetterPending, d.maxAttempts).
+				Order("block_number, log_index").
+				Limit(deadLetterBatch).
+				Find(&list).Error
+			if err != nil {
+				logger.WithModule("dead_letter").WithError(err).Error("load dead letters failed")
+				continue
+			}
+			for i := range list {
+				if err := d.retry(ctx, &list[i]); err != nil {
+					logger.WithModule("dead_letter").WithError(err).WithFields(logrus.Fields{
+						"id":       list[i].ID,
+						"attempts": list[i].Attempts,
+					}).Warn("retry dead letter failed")
+				}
+			}
+		}
+	}
+}
+
+// retry 重新解析入库, 成功标记 resolved, 失败累加尝试次数
+func (d *deadLetterService) retry(ctx context.Context, entry *models.DeadLetterEvent) error {
+	logEntry, err := deadLetterLog(entry)
+	if err == nil {
+		target := WatchTarget{Kind: entry.Kind, Address: common.HexToAddress(entry.Contract)}
+		err = d.listener.PersistLog(ctx, target, logEntry)
+	}
+	if err != nil {
+		entry.Attempts++
+		entry.Error = err.Error()
+		if updateErr := models.DB.WithContext(ctx).Model(entry).Updates(map[string]interface{}{
+			"attempts": entry.Attempts,
+			"error":    entry.Error,
+		}).Error; updateErr != nil {
+			return errors.Join(err, updateErr)
+		}
+		return err
+	}
+	entry.Status = models.DeadLetterResolved
+	return models.DB.WithContext(ctx).Model(entry).Update("status", entry.Status).Error
+}
+
+// recordDeadLetter 记录入库失败的原始日志, 同一条日志重复失败时累加次数;
+// 已解决的条目再次失败时重新置为 pending, 已丢弃的条目保持丢弃
+func recordDeadLetter(tx *gorm.DB, kind string, logEntry types.Log, cause error) error {
+	topics := make([]string, len(logEntry.Topics))
+	for i, topic := range logEntry.Topics {
+		topics[i] = topic.Hex()
+	}
+	marshal, err := json.Marshal(topics)
+	if err != nil {
+		return err
+	}
+	var entry models.DeadLetterEvent
+	err = tx.Where("tx_hash=? and log_index=?", logEntry.TxHash.Hex(), logEntry.Index).First(&entry).Error
+	if err == nil {
+		updates := map[string]interface{}{
+			"error":    cause.Error(),
+			"attempts": gorm.Expr("attempts + 1"),
+		}
+		// 已丢弃的条目是人工确认不再处理的, 重复失败不重新打开
+		if entry.Status != models.DeadLetterDiscarded {
+			updates["status"] = models.DeadLetterPending
+		}
+		return tx.Model(&entry).Updates(updates).Error
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	entry = models.DeadLetterEvent{
+		Kind:        kind,
+		Contract:    logEntry.Address.Hex(),
+		TxHash:      logEntry.TxHash.Hex(),
+		LogIndex:    logEntry.Index,
+		BlockNumber: logEntry.BlockNumber,
+		BlockHash:   logEntry.BlockHash.Hex(),
+		Topics:      string(marshal),
+		Data:        hexutil.Encode(logEntry.Data),
+		Error:       cause.Error(),
+		Attempts:    1,
+		Status:      models.DeadLetterPending,
+	}
+	return tx.Create(&entry).Error
+}
+
+// deadLetterLog 还原死信中保存的原始日志
+func deadLetterLog(entry *models.DeadLetterEvent) (types.Log, error) {
+	var topics []string
+	if err := json.Unmarshal([]byte(entry.Topics), &topics); err != nil {
+		return types.Log{}, fmt.Errorf("decode topics: %w", err)
+	}
+	data, err := hexutil.Decode(entry.Data)
+	if err != nil {
+		return types.Log{}, fmt.Errorf("decode data: %w", err)
+	}
+	logEntry := types.Log{
+		Address:     common.HexToAddress(entry.Contract),
+		Data:        data,
+		BlockNumber: entry.BlockNumber,
+		TxHash:      common.HexToHash(entry.TxHash),
+		BlockHash:   common.HexToHash(entry.BlockHash),
+		Index:       entry.LogIndex,
+	}
+	for _, topic := range topics {
+		logEntry.Topics = append(logEntry.Topics, common.HexToHash(topic))
+	}
+	return logEntry, nil
+}
@@ -16,8 +16,8 @@ import (
 type ERC20TokenService interface {
 	Approve(ctx context.Context, contractAddress common.Address, spenderAddress common.Address, privateKey *ecdsa.PrivateKey, value *big.Int) (*types.Transaction, error)
 	Transfer(ctx context.Context, contractAddress common.Address, to common.Address, privateKey *ecdsa.PrivateKey, value *big.Int) (*types.Transaction, error)
-	BalanceOf(ctx context.Context, contractAddress common.Address, to common.Address) (*big.Int, error)
-	Allowance(ctx context.Context, contractAddress common.Address, ownerAddress common.Address, spenderAddress common.Address) (*big.Int, error)
+	BalanceOf(ctx context.Context, contractAddress common.Address, to common.Address, block BlockRef) (*big.Int, error)
+	Allowance(ctx context.Context, contractAddress common.Address, ownerAddress common.Address, spenderAddress common.Address, block BlockRef) (*big.Int, error)
 }
 
 type erc20TokenService struct {
@@ -68,28 +68,28 @@ func (e *erc20TokenService) Transfer(ctx context.Context, contractAddress common
 	}
 	return tx, nil
 }
-func (e *erc20TokenService) BalanceOf(ctx context.Context, contractAddress common.Address, to common.Address) (*big.Int, error) {
+func (e *erc20TokenService) BalanceOf(ctx context.Context, contractAddress common.Address, to common.Address, block BlockRef) (*big.Int, error) {
 	client := e.client
 	newErc20, err := erc20.NewErc20(contractAddress, client)
 	if err != nil {
 		return nil, fmt.Errorf("new erc20 contract: %w", err)
 	}
-	value, err := newErc20.BalanceOf(&bind.CallOpts{Context: ctx}, to)
+	value, err := newErc20.BalanceOf(block.callOpts(ctx), to)
 	if err != nil {
-		return nil, fmt.Errorf("balanceOf call: %w", err)
+		return nil, fmt.Errorf("balanceOf call: %w", block.callErr(err))
 	}
 	return value, nil
 }
 
-func (e *erc20TokenService) Allowance(ctx context.Context, contractAddress common.Address, ownerAddress common.Address, spenderAddress common.Address) (*big.Int, error) {
+func (e *erc20TokenService) Allowance(ctx context.Context, contractAddress common.Address, ownerAddress common.Address, spenderAddress common.Address, block BlockRef) (*big.Int, error) {
 	client := e.client
 	newErc20, err := erc20.NewErc20(contractAddress, client)
 	if err != nil {
 		return nil, fmt.Errorf("new erc20 contract: %w", err)
 	}
-	value, err := newErc20.Allowance(&bind.CallOpts{Context: ctx}, ownerAddress, spenderAddress)
+	value, err := newErc20.Allowance(block.callOpts(ctx), ownerAddress, spenderAddress)
 	if err != nil {
-		return nil, fmt.Errorf("allowance call: %w", err)
+		return nil, fmt.Errorf("allowance call: %w", block.callErr(err))
 	}
 	return value, nil
 }
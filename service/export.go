@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	ExportCSV   = "csv"
+	ExportJSONL = "jsonl"
+)
+
+// exportFlushRows 每写出多少行刷新一次, 避免在内存中堆积
+const exportFlushRows = 1000
+
+// exportTable 可导出的事件明细表
+type exportTable struct {
+	table     string
+	columns   []string // 事件参数列
+	addresses []string // 按地址过滤时匹配的列
+	amount    string   // 需要按 decimals 换算的金额列, 为空表示没有
+	token     string   // 金额对应的代币
+}
+
+var exportTables = map[string]exportTable{
+	"staked":                {"staking_event_staked", []string{"user", "amount"}, []string{"user"}, "amount", tokenStaking},
+	"withdrawn":             {"staking_event_withdrawn", []string{"user", "amount"}, []string{"user"}, "amount", tokenStaking},
+	"rewards_claimed":       {"staking_event_rewards_claimed", []string{"user", "amount"}, []string{"user"}, "amount", tokenReward},
+	"reward_rate_updated":   {"staking_event_reward_rate_updated", []string{"new_reward_rate"}, nil, "new_reward_rate", tokenReward},
+	"ownership_transferred": {"staking_event_ownership_transferred", []string{"previous_owner", "new_owner"}, []string{"previous_owner", "new_owner"}, "", ""},
+	"transfer":              {"erc20_event_transfer", []string{"from", "to", "value"}, []string{"from", "to"}, "value", tokenSelf},
+	"approval":              {"erc20_event_approval", []string{"owner", "spender", "value"}, []string{"owner", "spender"}, "value", tokenSelf},
+}
+
+// 所有明细表共有的列, 按此顺序输出在事件参数之前
+var exportBaseColumns = []string{"block_number", "block_timestamp", "tx_hash", "log_index", "contract"}
+
+// ExportQuery 导出条件, 为空的条件不过滤, 区块与时间区间都包含两端
+type ExportQuery struct {
+	Event     string
+	Format    string
+	Contract  *common.Address
+	Address   *common.Address
+	FromBlock *uint64
+	ToBlock   *uint64
+	FromTime  *uint64
+	ToTime    *uint64
+}
+
+type ExportService interface {
+	Events() []string
+	Validate(query ExportQuery) error
+	Export(ctx context.Context, w io.Writer, query ExportQuery) (int64, error)
+}
+
+type exportService struct {
+	tokens *tokenCache
+}
+
+func NewExportService(client *ethclient.Client) ExportService {
+	return &exportService{tokens: newTokenCache(client)}
+}
+
+func (e *exportService) Events() []string {
+	events := make([]string, 0, len(exportTables))
+	for event := range exportTables {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	return events
+}
+
+// Validate 开始写出前检查参数, 写出后出错只能中断输出
+func (e *exportService) Validate(query ExportQuery) error {
+	if _, ok := exportTables[query.Event]; !ok {
+		return fmt.Errorf("unknown event %q, expected one of %s", query.Event, strings.Join(e.Events(), "/"))
+	}
+	if query.Address != nil && len(exportTables[query.Event].addresses) == 0 {
+		return fmt.Errorf("event %q has no address column", query.Event)
+	}
+	if query.Format != ExportCSV && query.Format != ExportJSONL {
+		return fmt.Errorf("unknown format %q, expected %s/%s", query.Format, ExportCSV, ExportJSONL)
+	}
+	if query.FromBlock != nil && query.ToBlock != nil && *query.FromBlock > *query.ToBlock {
+		return fmt.Errorf("fromBlock %d is after toBlock %d", *query.FromBlock, *query.ToBlock)
+	}
+	if query.FromTime != nil && query.ToTime != nil && *query.FromTime > *query.ToTime {
+		return fmt.Errorf("fromTime %d is after toTime %d", *query.FromTime, *query.ToTime)
+	}
+	return nil
+}
+
+// Export 按 (区块, 日志索引) 顺序逐行读取并写出, 返回写出的行数
+func (e *exportService) Export(ctx context.Context, w io.Writer, query ExportQuery) (int64, error) {
+	if err := e.Validate(query); err != nil {
+		return 0, err
+	}
+	table := exportTables[query.Event]
+	columns := append(append([]string{}, exportBaseColumns...), table.columns...)
+	db := models.DB.WithContext(ctx).Table(table.table).Select("`" + strings.Join(columns, "`, `") + "`")
+	if query.Contract != nil {
+		db = db.Where("contract = ?", query.Contract.Hex())
+	}
+	if query.Address != nil {
+		clauses := make([]string, 0, len(table.addresses))
+		args := make([]interface{}, 0, len(table.addresses))
+		for _, column := range table.addresses {
+			clauses = append(clauses, "`"+column+"` = ?")
+			args = append(args, query.Address.Hex())
+		}
+		db = db.Where("("+strings.Join(clauses, " OR ")+")", args...)
+	}
+	if query.FromBlock != nil {
+		db = db.Where("block_number >= ?", *query.FromBlock)
+	}
+	if query.ToBlock != nil {
+		db = db.Where("block_number <= ?", *query.ToBlock)
+	}
+	if query.FromTime != nil {
+		db = db.Where("block_timestamp >= ?", *query.FromTime)
+	}
+	if query.ToTime != nil {
+		db = db.Where("block_timestamp <= ?", *query.ToTime)
+	}
+	rows, err := db.Order("block_number").Order("log_index").Rows()
+	if err != nil {
+		return 0, fmt.Errorf("query %s: %w", table.table, err)
+	}
+	defer rows.Close()
+
+	header := columns
+	amountIndex, contractIndex := -1, -1
+	for i, column := range columns {
+		switch column {
+		case table.amount:
+			amountIndex = i
+		case "contract":
+			contractIndex = i
+		}
+	}
+	if amountIndex >= 0 {
+		header = append(append([]string{}, columns...), table.amount+"_formatted", "token_symbol")
+	}
+	out := newExportWriter(w, query.Format, header)
+	if err := out.header(); err != nil {
+		return 0, err
+	}
+
+	values := make([]string, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(header))
+	// 本次导出内缓存解析失败的合约, 避免每行都请求节点
+	failed := make(map[string]bool)
+	var count int64
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return count, fmt.Errorf("scan %s: %w", table.table, err)
+		}
+		copy(record, values)
+		if amountIndex >= 0 {
+			record[len(columns)], record[len(columns)+1] = "", ""
+			contract := values[contractIndex]
+			if !failed[contract] {
+				token, err := e.tokens.resolve(ctx, common.HexToAddress(contract), table.token)
+				if err != nil {
+					failed[contract] = true
+					logger.WithModule("export").WithError(err).WithFields(logrus.Fields{
+						"contract": contract,
+						"event":    query.Event,
+					}).Warn("resolve token failed, formatted amount left empty")
+				} else {
+					record[len(columns)] = formatUnits(values[amountIndex], token.decimals)
+					record[len(columns)+1] = token.symbol
+				}
+			}
+		}
+		if err := out.write(record); err != nil {
+			return count, err
+		}
+		count++
+		if count%exportFlushRows == 0 {
+			if err := out.flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("iterate %s: %w", table.table, err)
+	}
+	return count, out.flush()
+}
+
+// exportWriter CSV 先写表头; JSONL 每行一个对象, 数字列输出为数字
+type exportWriter struct {
+	columns []string
+	csv     *csv.Writer
+	json    *json.Encoder
+}
+
+func newExportWriter(w io.Writer, format string, columns []string) *exportWriter {
+	out := &exportWriter{columns: columns}
+	if format == ExportCSV {
+		out.csv = csv.NewWriter(w)
+	} else {
+		out.json = json.NewEncoder(w)
+	}
+	return out
+}
+
+func (o *exportWriter) header() error {
+	if o.csv == nil {
+		return nil
+	}
+	return o.csv.Write(o.columns)
+}
+
+func (o *exportWriter) write(record []string) error {
+	if o.csv != nil {
+		return o.csv.Write(record)
+	}
+	row := make(map[string]interface{}, len(record))
+	for i, column := range o.columns {
+		switch column {
+		case "block_number", "block_timestamp", "log_index":
+			row[column] = json.Number(record[i])
+		default:
+			row[column] = record[i]
+		}
+	}
+	if err := o.json.Encode(row); err != nil {
+		return fmt.Errorf("write jsonl: %w", err)
+	}
+	return nil
+}
+
+func (o *exportWriter) flush() error {
+	if o.csv == nil {
+		return nil
+	}
+	o.csv.Flush()
+	if err := o.csv.Error(); err != nil {
+		return fmt.Errorf("write csv: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"golang.org/x/sync/errgroup"
+)
+
+const (
+	// 缓存的区块时间数量
+	headerCacheSize = 4096
+	// 并发拉取区块头的数量
+	headerFetchWorkers = 8
+)
+
+// headerCache 按区块高度缓存区块哈希与时间, 避免同一区块的多条日志重复请求区块头;
+// 命中时仍比较哈希, 重组后同高度的旧区块不会被当作规范链上的区块
+type headerCache struct {
+	client bind.ContractBackend
+	mu     sync.Mutex
+	blocks map[uint64]cachedHeader
+	order  []uint64
+}
+
+type cachedHeader struct {
+	hash common.Hash
+	time uint64
+}
+
+func newHeaderCache(client bind.ContractBackend) *headerCache {
+	return &headerCache{client: client, blocks: make(map[uint64]cachedHeader)}
+}
+
+func (c *headerCache) get(number uint64) (cachedHeader, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	block, ok := c.blocks[number]
+	return block, ok
+}
+
+// put 记录最新取到的区块头, 同高度的旧记录直接覆盖
+func (c *headerCache) put(number uint64, hash common.Hash, t uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.blocks[number]; !ok {
+		if len(c.order) >= headerCacheSize {
+			delete(c.blocks, c.order[0])
+			c.order = c.order[1:]
+		}
+		c.order = append(c.order, number)
+	}
+	c.blocks[number] = cachedHeader{hash: hash, time: t}
+}
+
+// blockTime 返回区块时间; 缓存中同高度的哈希一致时直接返回, 否则按高度读取区块头.
+// 重组时同高度可能已是另一个区块, 哈希不一致直接报错, 由下一轮重新拉取
+func (c *headerCache) blockTime(ctx context.Context, number uint64, hash common.Hash) (uint64, error) {
+	if block, ok := c.get(number); ok && block.hash == hash {
+		return block.time, nil
+	}
+	return c.fetchBlockTime(ctx, number, hash)
+}
+
+// fetchBlockTime 读取区块头确认 hash 仍在规范链上, 并刷新缓存
+func (c *headerCache) fetchBlockTime(ctx context.Context, number uint64, hash common.Hash) (uint64, error) {
+	header, err := c.client.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+	if err != nil {
+		return 0, err
+	}
+	c.put(number, header.Hash(), header.Time)
+	if header.Hash() != hash {
+		return 0, fmt.Errorf("block %d hash mismatch: log %s, header %s", number, hash.Hex(), header.Hash().Hex())
+	}
+	return header.Time, nil
+}
+
+// fillBlockTimestamps 为节点未返回 blockTimestamp 的日志补齐区块时间,
+// 同一区块只请求一次, 不同区块并发请求
+func (c *headerCache) fillBlockTimestamps(ctx context.Context, logs []types.Log) error {
+	missing := make(map[common.Hash]uint64)
+	for i := range logs {
+		if logs[i].BlockTimestamp == 0 {
+			missing[logs[i].BlockHash] = logs[i].BlockNumber
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	var mu sync.Mutex
+	times := make(map[common.Hash]uint64, len(missing))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(headerFetchWorkers)
+	for hash, number := range missing {
+		g.Go(func() error {
+			t, err := c.blockTime(gctx, number, hash)
+			if err != nil {
+				return err
+			}
+			mu.Lock()
+			times[hash] = t
+			mu.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	for i := range logs {
+		if logs[i].BlockTimestamp == 0 {
+			logs[i].BlockTimestamp = times[logs[i].BlockHash]
+		}
+	}
+	return nil
+}
+
+// fillBlockTimestamp 单条日志 (死信重试) 不经过回放的区块头校验, 总是重新读取区块头确认仍在规范链上
+func (c *headerCache) fillBlockTimestamp(ctx context.Context, logEntry *types.Log) error {
+	t, err := c.fetchBlockTime(ctx, logEntry.BlockNumber, logEntry.BlockHash)
+	if err != nil {
+		return err
+	}
+	logEntry.BlockTimestamp = t
+	return nil
+}
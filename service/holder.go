@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-solidity-staking/models"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 铸造/销毁的对手方, 不计入持有人
+var zeroAddress = common.Address{}.Hex()
+
+type HolderService interface {
+	Top(ctx context.Context, contract common.Address, pageNum int, pageSize int) ([]models.ERC20HolderBalance, int64, error)
+	Count(ctx context.Context, contract common.Address) (int64, error)
+	BalanceAt(ctx context.Context, contract common.Address, holder common.Address, blockNumber *uint64) (*HolderBalance, error)
+	Rebuild(ctx context.Context, contract common.Address) error
+}
+
+// HolderBalance 按已索引 Transfer 计算的余额, blockNumber 为空表示最新已索引状态
+type HolderBalance struct {
+	Contract    string  `json:"contract"`
+	Holder      string  `json:"holder"`
+	BlockNumber *uint64 `json:"blockNumber"`
+	Balance     string  `json:"balance"`
+}
+
+type holderService struct{}
+
+func NewHolderService() HolderService {
+	return &holderService{}
+}
+
+// Top 余额大于 0 的持有人, 按余额倒序
+func (h *holderService) Top(ctx context.Context, contract common.Address, pageNum int, pageSize int) ([]models.ERC20HolderBalance, int64, error) {
+	query := models.DB.WithContext(ctx).Model(&models.ERC20HolderBalance{}).Where("contract = ? and balance > 0", contract.Hex())
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count holders: %w", err)
+	}
+	var list []models.ERC20HolderBalance
+	err := query.Order("balance desc").Order("id").
+		Offset((pageNum - 1) * pageSize).
+		Limit(pageSize).
+		Find(&list).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list holders: %w", err)
+	}
+	return list, total, nil
+}
+
+func (h *holderService) Count(ctx context.Context, contract common.Address) (int64, error) {
+	var total int64
+	err := models.DB.WithContext(ctx).Model(&models.ERC20HolderBalance{}).Where("contract = ? and balance > 0", contract.Hex()).Count(&total).Error
+	if err != nil {
+		return 0, fmt.Errorf("count holders: %w", err)
+	}
+	return total, nil
+}
+
+// BalanceAt 指定区块时按明细求和, 否则读取余额账本
+func (h *holderService) BalanceAt(ctx context.Context, contract common.Address, holder common.Address, blockNumber *uint64) (*HolderBalance, error) {
+	result := &HolderBalance{Contract: contract.Hex(), Holder: holder.Hex(), BlockNumber: blockNumber, Balance: "0"}
+	if blockNumber != nil {
+		balances, err := holderBalancesAt(models.DB.WithContext(ctx), contract.Hex(), *blockNumber, []string{holder.Hex()})
+		if err != nil {
+			return nil, err
+		}
+		if balance, ok := balances[holder.Hex()]; ok {
+			result.Balance = balance.String()
+		}
+		return result, nil
+	}
+	var entry models.ERC20HolderBalance
+	err := models.DB.WithContext(ctx).Where("contract = ? and holder = ?", contract.Hex(), holder.Hex()).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get holder balance: %w", err)
+	}
+	result.Balance = entry.Balance
+	return result, nil
+}
+
+// Rebuild 按 Transfer 明细重算代币的全部余额
+func (h *holderService) Rebuild(ctx context.Context, contract common.Address) error {
+	return models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return rebuildHolderBalances(tx, contract.Hex(), nil)
+	})
+}
+
+// applyTransferBalance 新写入一条 Transfer 明细后增量更新双方余额, 只在明细首次入库时调用
+func applyTransferBalance(tx *gorm.DB, logEntry types.Log, from common.Address, to common.Address, value *big.Int) error {
+	for _, change := range []struct {
+		holder common.Address
+		amount *big.Int
+	}{
+		{from, new(big.Int).Neg(value)},
+		{to, value},
+	} {
+		if change.holder.Hex() == zeroAddress {
+			continue
+		}
+		entry := models.ERC20HolderBalance{
+			Contract:   logEntry.Address.Hex(),
+			Holder:     change.holder.Hex(),
+			Balance:    change.amount.String(),
+			FirstBlock: logEntry.BlockNumber,
+			LastBlock:  logEntry.BlockNumber,
+			UpdatedAt:  time.Now(),
+		}
+		err := tx.Clauses(clause.OnConflict{
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"balance":     gorm.Expr("balance + VALUES(balance)"),
+				"first_block": gorm.Expr("LEAST(first_block, VALUES(first_block))"),
+				"last_block":  gorm.Expr("GREATEST(last_block, VALUES(last_block))"),
+				"updated_at":  gorm.Expr("VALUES(updated_at)"),
+			}),
+		}).Create(&entry).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectHolderAffected 删除 Transfer 明细前调用, 返回涉及的持有人
+func collectHolderAffected(tx *gorm.DB, contract string, where string, args ...interface{}) ([]string, error) {
+	var rows []struct {
+		From string
+		To   string
+	}
+	err := tx.Model(&models.ERC20EventTransfer{}).Select("`from`, `to`").
+		Where("contract = ?", contract).Where(where, args...).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	holders := []string{}
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		for _, holder := range []string{row.From, row.To} {
+			if holder != zeroAddress && !seen[holder] {
+				seen[holder] = true
+				holders = append(holders, holder)
+			}
+		}
+	}
+	return holders, nil
+}
+
+// rebuildHolderBalances 按明细重算余额; holders 为 nil 时重算代币的全部持有人
+func rebuildHolderBalances(tx *gorm.DB, contract string, holders []string) error {
+	if holders != nil && len(holders) == 0 {
+		return nil
+	}
+	toScope, fromScope := "contract = ?", "contract = ?"
+	toArgs, fromArgs := []interface{}{contract}, []interface{}{contract}
+	deleteQuery := tx.Where("contract = ?", contract)
+	if holders != nil {
+		toScope += " and `to` IN ?"
+		fromScope += " and `from` IN ?"
+		toArgs = append(toArgs, holders)
+		fromArgs = append(fromArgs, holders)
+		deleteQuery = deleteQuery.Where("holder IN ?", holders)
+	}
+	if err := deleteQuery.Delete(&models.ERC20HolderBalance{}).Error; err != nil {
+		return err
+	}
+	args := append(append(toArgs, fromArgs...), zeroAddress)
+	return tx.Exec(`INSERT INTO erc20_holder_balance
+  (contract, holder, balance, first_block, last_block, updated_at)
+SELECT contract, holder, SUM(amount), MIN(block_number), MAX(block_number), NOW()
+FROM (
+  SELECT contract, `+"`to`"+` AS holder, CAST(value AS DECIMAL(65,0)) AS amount, block_number
+  FROM erc20_event_transfer WHERE `+toScope+`
+  UNION ALL
+  SELECT contract, `+"`from`"+`, -CAST(value AS DECIMAL(65,0)), block_number
+  FROM erc20_event_transfer WHERE `+fromScope+`
+) t
+WHERE holder <> ?
+GROUP BY contract, holder`, args...).Error
+}
+
+// holderBalancesAt 截至 height 区块 (含) 的余额: 转入减转出
+func holderBalancesAt(db *gorm.DB, contract string, height uint64, holders []string) (map[string]*big.Int, error) {
+	var rows []struct {
+		Holder  string
+		Balance string
+	}
+	err := db.Raw(`SELECT holder, CAST(SUM(amount) AS CHAR) AS balance FROM (
+  SELECT `+"`to`"+` AS holder, CAST(value AS DECIMAL(65,0)) AS amount FROM erc20_event_transfer WHERE contract = ? AND block_number <= ? AND `+"`to`"+` IN ?
+  UNION ALL
+  SELECT `+"`from`"+`, -CAST(value AS DECIMAL(65,0)) FROM erc20_event_transfer WHERE contract = ? AND block_number <= ? AND `+"`from`"+` IN ?
+) t GROUP BY holder`, contract, height, holders, contract, height, holders).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("sum indexed balances: %w", err)
+	}
+	balances := make(map[string]*big.Int, len(rows))
+	for _, row := range rows {
+		balance, ok := new(big.Int).SetString(row.Balance, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid indexed balance %q of %s", row.Balance, row.Holder)
+		}
+		balances[row.Holder] = balance
+	}
+	return balances, nil
+}
@@ -4,50 +4,68 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"go-solidity-staking/gen/erc20"
 	"go-solidity-staking/gen/staking"
 	"go-solidity-staking/logger"
 	"go-solidity-staking/models"
 	"math/big"
+	"sort"
 	"strings"
 	"time"
 
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
 const (
 	StakingPrefix = "staking"
 	ERC20Prefix   = "erc20_transfer"
+	ABIPrefix     = "abi"
 )
 
+const (
+	ContractKindStaking = "staking"
+	ContractKindERC20   = "erc20"
+	ContractKindABI     = "abi" // 按 ABI 通用解析, 只写 event_log
+)
+
+// WatchTarget 需要索引的合约
+type WatchTarget struct {
+	Kind    string
+	Address common.Address
+	AbiName string // ContractKindABI 使用的 ABI 名称
+}
+
 type ListenerService interface {
-	ReplayFromLast(ctx context.Context, contractAddress common.Address, starkBlock uint64, confirmations uint64) error
-	StartReplayLoop(ctx context.Context, contractAddress common.Address, starkBlock uint64, confirmations uint64, interval time.Duration)
-	ReplayERC20FromLast(ctx context.Context, contractAddress common.Address, starkBlock uint64, confirmations uint64) error
-	StartERC20ReplayLoop(ctx context.Context, contractAddress common.Address, starkBlock uint64, confirmations uint64, interval time.Duration)
+	ReplayFromLast(ctx context.Context, targets []WatchTarget, starkBlock uint64, confirmations uint64) error
+	StartReplayLoop(ctx context.Context, targets []WatchTarget, starkBlock uint64, confirmations uint64, interval time.Duration)
+	StartSubscribeLoop(ctx context.Context, targets []WatchTarget, starkBlock uint64, confirmations uint64, interval time.Duration)
+	PersistLog(ctx context.Context, target WatchTarget, logEntry types.Log) error
+	ReindexRange(ctx context.Context, target WatchTarget, from uint64, to uint64, confirmations uint64, purge bool) error
 }
 
 type listenerService struct {
-	client *ethclient.Client
+	client  bind.ContractBackend
+	chunks  *chunkSizer
+	headers *headerCache
+	abis    AbiStore
 }
 
-func NewListenerService(client *ethclient.Client) ListenerService {
-	return &listenerService{client: client}
+// NewListenerService client 可以是 ethclient 或模拟链 (simulated backend)
+func NewListenerService(client bind.ContractBackend, chunk ChunkConfig, abis AbiStore) ListenerService {
+	return &listenerService{client: client, chunks: newChunkSizer(chunk), headers: newHeaderCache(client), abis: abis}
 }
 
-func (l *listenerService) ReplayFromLast(ctx context.Context, contractAddress common.Address, starkBlock uint64, confirmations uint64) error {
-	// 读取上次同步的区块
-	key := syncKey(StakingPrefix, contractAddress)
-	lastBlock, err := l.getSyncBlock(key)
-	if err != nil {
-		return err
-	}
-	if lastBlock == 0 && starkBlock > 0 {
-		lastBlock = starkBlock - 1
+// ReplayFromLast 从各合约检查点中最小的区块开始, 一次 eth_getLogs 回放所有合约的事件
+func (l *listenerService) ReplayFromLast(ctx context.Context, targets []WatchTarget, starkBlock uint64, confirmations uint64) error {
+	if len(targets) == 0 {
+		return nil
 	}
 	// 获取最新区块
 	latestHeader, err := l.client.HeaderByNumber(ctx, nil)
@@ -60,15 +78,56 @@ func (l *listenerService) ReplayFromLast(ctx context.Context, contractAddress co
 	if confirmations > 1 && latest >= confirmations-1 {
 		latest = latest - (confirmations - 1)
 	}
+	keys := make([]string, 0, len(targets))
+	from := latest
+	for _, target := range targets {
+		// 读取上次同步的区块
+		key := target.syncKey()
+		lastBlock, err := l.getSyncBlock(key)
+		if err != nil {
+			return err
+		}
+		if lastBlock == 0 && starkBlock > 0 {
+			lastBlock = starkBlock - 1
+		}
+		// 链重组检测
+		if lastBlock < latest {
+			lastBlock, err = l.detectReorg(ctx, key, target.Address, target.eventModels(), lastBlock)
+			if err != nil {
+				return err
+			}
+		}
+		if lastBlock < from {
+			from = lastBlock
+		}
+		keys = append(keys, key)
+	}
 	// 区块已同步
-	if lastBlock > latest {
+	if from >= latest {
 		return nil
 	}
-	// 回放
-	return l.replayRange(ctx, contractAddress, lastBlock+1, latest)
+	// 记录最新已确认区块之下 reorgWindow 个区块的哈希, 用于查找分叉点
+	opts := rangeOptions{checkpoint: true, hashesFrom: hashWindowStart(latest)}
+	// 落后多个区间时并发拉取, 按区块顺序提交
+	if l.chunks.cfg.Concurrency > 1 && latest-from > l.chunks.get(strings.Join(keys, ",")) {
+		err = l.replayParallel(ctx, strings.Join(keys, ","), targets, from+1, latest, opts)
+	} else {
+		// 分段回放, 每段结束写入检查点
+		err = l.replayChunked(ctx, strings.Join(keys, ","), from+1, latest, func(start uint64, end uint64) error {
+			return l.replayRange(ctx, targets, start, end, opts)
+		})
+	}
+	// 两个区间的拉取之间发生了重组, 已提交的区间可能在旧链上
+	if errors.Is(err, errRangeUnlinked) {
+		logger.WithModule("listener").WithError(err).Warn("replay range not linked to checkpoint")
+		if rbErr := l.rollbackUnlinked(ctx, targets); rbErr != nil {
+			return errors.Join(err, rbErr)
+		}
+	}
+	return err
 }
 
-func (l *listenerService) StartReplayLoop(ctx context.Context, contractAddress common.Address, starkBlock uint64, confirmations uint64, interval time.Duration) {
+func (l *listenerService) StartReplayLoop(ctx context.Context, targets []WatchTarget, starkBlock uint64, confirmations uint64, interval time.Duration) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 	for {
@@ -76,77 +135,349 @@ func (l *listenerService) StartReplayLoop(ctx context.Context, contractAddress c
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := l.ReplayFromLast(ctx, contractAddress, starkBlock, confirmations); err != nil {
+			if err := l.ReplayFromLast(ctx, targets, starkBlock, confirmations); err != nil {
 				logger.WithModule("listener").WithError(err).Error("start replay loop failed")
 			}
 		}
 	}
 }
 
-func (l *listenerService) replayRange(ctx context.Context, contractAddress common.Address, start uint64, end uint64) error {
-	s, err := staking.NewStaking(contractAddress, l.client)
+// replayRange 用一次 FilterLogs 拉取区间内所有合约、所有事件签名的日志,
+// 按 (区块, 日志索引) 排序后依次解析入库
+func (l *listenerService) replayRange(ctx context.Context, targets []WatchTarget, start uint64, end uint64, opts rangeOptions) error {
+	return l.indexRange(ctx, targets, start, end, opts)
+}
+
+// rangeOptions 控制区间索引的附加行为
+type rangeOptions struct {
+	checkpoint   bool   // 提交时推进 sync_state, 重新索引时不动检查点
+	hashesFrom   uint64 // 推进检查点时记录 [hashesFrom, end] 每个区块的哈希, 区间末块总会记录
+	purge        bool   // 写入前先删除区间内已有的事件行
+	verifyHashes bool   // 重新索引时按 sync_block 记录的哈希校验日志所在区块
+}
+
+func (l *listenerService) indexRange(ctx context.Context, targets []WatchTarget, start uint64, end uint64, opts rangeOptions) error {
+	batch, err := l.fetchRange(ctx, targets, start, end, opts)
 	if err != nil {
 		return err
 	}
-	endCopy := end
-	if err := l.consumeStakingStaked(s, ctx, start, &endCopy); err != nil {
-		logger.WithModule("listener").WithError(err).Error("replay range consume staking staked failed")
-		return err
+	return l.commitRange(batch, opts)
+}
+
+// rangeBatch 已拉取并解析完成、等待入库的区间
+type rangeBatch struct {
+	targets []WatchTarget
+	start   uint64
+	end     uint64
+	logs    []decodedLog
+	blocks  []blockHash // 需要记录哈希的区块, 按高度升序, 最后一个为区间末块; 只在推进检查点时拉取
+	parent  common.Hash // 区间首块的 parentHash, 只在推进检查点时拉取
+}
+
+// decodedLog 解析结果: 解析失败的日志在入库时写入死信
+type decodedLog struct {
+	kind  string
+	log   types.Log
+	write logWriter
+	err   error
+}
+
+// fetchRange 拉取并解析区间内的日志, 不访问数据库, 可并发执行
+func (l *listenerService) fetchRange(ctx context.Context, targets []WatchTarget, start uint64, end uint64, opts rangeOptions) (*rangeBatch, error) {
+	query := ethereum.FilterQuery{
+		FromBlock: new(big.Int).SetUint64(start),
+		ToBlock:   new(big.Int).SetUint64(end),
+	}
+	decoders := make(map[common.Address]logDecoder, len(targets))
+	kinds := make(map[common.Address]string, len(targets))
+	var topics []common.Hash
+	seen := make(map[common.Hash]bool)
+	for _, target := range targets {
+		decoder, targetTopics, err := l.newLogDecoder(target)
+		if err != nil {
+			return nil, err
+		}
+		// 汇总所有合约需要索引的事件签名 (topic0)
+		for _, topic := range targetTopics {
+			if !seen[topic] {
+				seen[topic] = true
+				topics = append(topics, topic)
+			}
+		}
+		query.Addresses = append(query.Addresses, target.Address)
+		decoders[target.Address] = decoder
+		kinds[target.Address] = target.Kind
+	}
+	query.Topics = [][]common.Hash{topics}
+	// 先取区块头再取日志, 日志所在区块与区块头不一致说明期间发生了重组
+	var blocks []blockHash
+	var parent common.Hash
+	if opts.checkpoint {
+		var err error
+		blocks, parent, err = l.fetchBlockHashes(ctx, start, end, opts.hashesFrom)
+		if err != nil {
+			return nil, err
+		}
+	}
+	logs, err := l.client.FilterLogs(ctx, query)
+	if err != nil {
+		logger.WithModule("listener").WithError(err).Error("replay range filter logs failed")
+		return nil, err
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		if logs[i].BlockNumber != logs[j].BlockNumber {
+			return logs[i].BlockNumber < logs[j].BlockNumber
+		}
+		return logs[i].Index < logs[j].Index
+	})
+	if err := checkLogHashes(logs, blocks); err != nil {
+		return nil, err
+	}
+	// 补齐区块时间
+	if err := l.headers.fillBlockTimestamps(ctx, logs); err != nil {
+		return nil, err
+	}
+	batch := &rangeBatch{targets: targets, start: start, end: end, parent: parent, blocks: blocks, logs: make([]decodedLog, 0, len(logs))}
+	for _, logEntry := range logs {
+		decoder, ok := decoders[logEntry.Address]
+		if !ok || len(logEntry.Topics) == 0 {
+			continue
+		}
+		write, err := decoder(logEntry)
+		if write == nil && err == nil {
+			continue
+		}
+		batch.logs = append(batch.logs, decodedLog{kind: kinds[logEntry.Address], log: logEntry, write: write, err: err})
+	}
+	return batch, nil
+}
+
+// commitRange 事件、明细与检查点在同一事务提交, 数据库失败回滚整个区间, 下次 tick 重试;
+// 单条日志解析或入库失败时回滚到保存点并写入死信, 不阻塞后续区间
+func (l *listenerService) commitRange(batch *rangeBatch, opts rangeOptions) error {
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		if opts.checkpoint {
+			if err := checkRangeLink(tx, batch); err != nil {
+				return err
+			}
+		}
+		if opts.verifyHashes {
+			for _, target := range batch.targets {
+				if err := checkRecordedHashes(tx, target.syncKey(), batch.logs); err != nil {
+					return err
+				}
+			}
+		}
+		if opts.purge {
+			for _, target := range batch.targets {
+				if err := purgeRange(tx, target, batch.start, batch.end); err != nil {
+					return err
+				}
+			}
+		}
+		for _, decoded := range batch.logs {
+			if err := l.persistLog(tx, decoded); err != nil {
+				return err
+			}
+		}
+		if !opts.checkpoint {
+			return nil
+		}
+		for _, target := range batch.targets {
+			if err := l.setSyncBlock(tx, target.syncKey(), batch.blocks); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (l *listenerService) persistLog(tx *gorm.DB, decoded decodedLog) error {
+	err := decoded.err
+	if err == nil {
+		if err := tx.SavePoint("persist_log").Error; err != nil {
+			return err
+		}
+		if err = decoded.write(tx); err == nil {
+			return nil
+		}
+		if rbErr := tx.RollbackTo("persist_log").Error; rbErr != nil {
+			return rbErr
+		}
 	}
-	if err := l.consumeStakingWithdrawn(s, ctx, start, &endCopy); err != nil {
-		logger.WithModule("listener").WithError(err).Error("replay range consume staking withdrawn failed")
+	logger.WithModule("listener").WithError(err).WithFields(logrus.Fields{
+		"tx_hash":   decoded.log.TxHash.Hex(),
+		"log_index": decoded.log.Index,
+	}).Error("persist log failed, moving to dead letter")
+	return recordDeadLetter(tx, decoded.kind, decoded.log, err)
+}
+
+// PersistLog 单独解析入库一条日志, 供死信重试使用
+func (l *listenerService) PersistLog(ctx context.Context, target WatchTarget, logEntry types.Log) error {
+	if len(logEntry.Topics) == 0 {
+		return errors.New("log has no topics")
+	}
+	decoder, _, err := l.newLogDecoder(target)
+	if err != nil {
 		return err
 	}
-	if err := l.consumeStakingRewardsClaimed(s, ctx, start, &endCopy); err != nil {
-		logger.WithModule("listener").WithError(err).Error("replay range consume staking reward claimed failed")
+	if err := l.headers.fillBlockTimestamp(ctx, &logEntry); err != nil {
 		return err
 	}
-	if err := l.consumeStakingRewardRateUpdated(s, ctx, start, &endCopy); err != nil {
-		logger.WithModule("listener").WithError(err).Error("replay range consume staking reward rate updated failed")
+	write, err := decoder(logEntry)
+	if err != nil || write == nil {
 		return err
 	}
-	return l.setSyncBlock(syncKey(StakingPrefix, contractAddress), end)
+	return models.DB.WithContext(ctx).Transaction(write)
+}
+
+// logDecoder 解析单条日志, 返回写入对应 handle 的函数; 不关心的事件返回 nil
+type logDecoder func(logEntry types.Log) (logWriter, error)
+
+type logWriter func(tx *gorm.DB) error
+
+// newLogDecoder 返回合约的日志解析函数及需要索引的事件签名
+func (l *listenerService) newLogDecoder(target WatchTarget) (logDecoder, []common.Hash, error) {
+	switch target.Kind {
+	case ContractKindStaking:
+		s, err := staking.NewStaking(target.Address, l.client)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(logEntry types.Log) (logWriter, error) {
+			return l.decodeStakingLog(s, logEntry)
+		}, eventIDs(stakingABI, stakingEvents), nil
+	case ContractKindERC20:
+		token, err := erc20.NewErc20(target.Address, l.client)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(logEntry types.Log) (logWriter, error) {
+			return l.decodeErc20Log(token, logEntry)
+		}, eventIDs(erc20ABI, erc20Events), nil
+	case ContractKindABI:
+		parsed, err := l.resolveABI(target)
+		if err != nil {
+			return nil, nil, err
+		}
+		return func(logEntry types.Log) (logWriter, error) {
+			return l.decodeABILog(parsed, logEntry)
+		}, abiTopics(parsed), nil
+	}
+	return nil, nil, fmt.Errorf("unknown contract kind %q", target.Kind)
 }
-func (l *listenerService) handleStaked(ev *staking.StakingStaked) {
+
+func (l *listenerService) decodeStakingLog(s *staking.Staking, logEntry types.Log) (logWriter, error) {
+	switch logEntry.Topics[0] {
+	case stakingABI.Events["Staked"].ID:
+		ev, err := s.ParseStaked(logEntry)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *gorm.DB) error { return l.handleStaked(tx, ev) }, nil
+	case stakingABI.Events["Withdrawn"].ID:
+		ev, err := s.ParseWithdrawn(logEntry)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *gorm.DB) error { return l.handleWithdrawn(tx, ev) }, nil
+	case stakingABI.Events["RewardsClaimed"].ID:
+		ev, err := s.ParseRewardsClaimed(logEntry)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *gorm.DB) error { return l.handleRewardsClaimed(tx, ev) }, nil
+	case stakingABI.Events["RewardRateUpdated"].ID:
+		ev, err := s.ParseRewardRateUpdated(logEntry)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *gorm.DB) error { return l.handleRewardRateUpdated(tx, ev) }, nil
+	case stakingABI.Events["OwnershipTransferred"].ID:
+		ev, err := s.ParseOwnershipTransferred(logEntry)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *gorm.DB) error { return l.handleOwnershipTransferred(tx, ev) }, nil
+	}
+	return nil, nil
+}
+
+func (l *listenerService) decodeErc20Log(token *erc20.Erc20, logEntry types.Log) (logWriter, error) {
+	switch logEntry.Topics[0] {
+	case erc20ABI.Events["Transfer"].ID:
+		ev, err := token.ParseTransfer(logEntry)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *gorm.DB) error { return l.handleErc20Transfer(tx, ev) }, nil
+	case erc20ABI.Events["Approval"].ID:
+		ev, err := token.ParseApproval(logEntry)
+		if err != nil {
+			return nil, err
+		}
+		return func(tx *gorm.DB) error { return l.handleErc20Approval(tx, ev) }, nil
+	}
+	return nil, nil
+}
+
+func (l *listenerService) handleStaked(tx *gorm.DB, ev *staking.StakingStaked) error {
 	if ev == nil {
-		return
+		return nil
 	}
-	ok, err := l.recordEvent(ev.Raw, "staked")
+	ok, err := l.recordEvent(tx, ev.Raw, "staked")
 	if err != nil || !ok {
-		return
+		return err
+	}
+	created, err := l.recordStakedDetail(tx, ev)
+	if err != nil || !created {
+		return err
 	}
-	_, _ = l.recordStakedDetail(ev)
-	// 更新区块高度
-	_ = l.setSyncBlock(syncKey(StakingPrefix, ev.Raw.Address), ev.Raw.BlockNumber)
+	if err := applyPosition(tx, ev.Raw, ev.User, ev.Amount, common.Big0, common.Big0); err != nil {
+		return err
+	}
+	return markRollupDirty(tx, ev.Raw.Address.Hex(), ev.Raw.BlockTimestamp)
 }
 
-func (l *listenerService) handleWithdrawn(ev *staking.StakingWithdrawn) {
+func (l *listenerService) handleWithdrawn(tx *gorm.DB, ev *staking.StakingWithdrawn) error {
 	if ev == nil {
-		return
+		return nil
 	}
-	ok, err := l.recordEvent(ev.Raw, "withdrawn")
+	ok, err := l.recordEvent(tx, ev.Raw, "withdrawn")
 	if err != nil || !ok {
-		return
+		return err
+	}
+	created, err := l.recordWithdrawnDetail(tx, ev)
+	if err != nil || !created {
+		return err
 	}
-	_, _ = l.recordWithdrawnDetail(ev)
-	_ = l.setSyncBlock(syncKey(StakingPrefix, ev.Raw.Address), ev.Raw.BlockNumber)
+	if err := applyPosition(tx, ev.Raw, ev.User, common.Big0, ev.Amount, common.Big0); err != nil {
+		return err
+	}
+	return markRollupDirty(tx, ev.Raw.Address.Hex(), ev.Raw.BlockTimestamp)
 }
 
-func (l *listenerService) handleRewardsClaimed(ev *staking.StakingRewardsClaimed) {
+func (l *listenerService) handleRewardsClaimed(tx *gorm.DB, ev *staking.StakingRewardsClaimed) error {
 	if ev == nil {
-		return
+		return nil
 	}
-	ok, err := l.recordEvent(ev.Raw, "rewards_claimed")
+	ok, err := l.recordEvent(tx, ev.Raw, "rewards_claimed")
 	if err != nil || !ok {
-		return
+		return err
 	}
-	_, _ = l.recordRewardsClaimedDetail(ev)
-	_ = l.setSyncBlock(syncKey(StakingPrefix, ev.Raw.Address), ev.Raw.BlockNumber)
+	created, err := l.recordRewardsClaimedDetail(tx, ev)
+	if err != nil || !created {
+		return err
+	}
+	if err := applyPosition(tx, ev.Raw, ev.User, common.Big0, common.Big0, ev.Amount); err != nil {
+		return err
+	}
+	return markRollupDirty(tx, ev.Raw.Address.Hex(), ev.Raw.BlockTimestamp)
 }
 
-func (l *listenerService) handleRewardRateUpdated(ev *staking.StakingRewardRateUpdated) {
+func (l *listenerService) handleRewardRateUpdated(tx *gorm.DB, ev *staking.StakingRewardRateUpdated) error {
 	if ev == nil {
-		return
+		return nil
 	}
 	signature := ""
 	if len(ev.Raw.Topics) > 0 {
@@ -156,112 +487,82 @@ func (l *listenerService) handleRewardRateUpdated(ev *staking.StakingRewardRateU
 		"signature":       signature,
 		"new_reward_rate": ev.NewRewardRate.String(),
 	}
-	ok, err := l.recordEventMap(ev.Raw, "reward_rate_updated", indexedMap)
+	ok, err := l.recordEventMap(tx, ev.Raw, "reward_rate_updated", indexedMap)
 	if err != nil || !ok {
-		return
+		return err
 	}
-	_, _ = l.recordRewardRateUpdatedDetail(ev)
-	_ = l.setSyncBlock(syncKey(StakingPrefix, ev.Raw.Address), ev.Raw.BlockNumber)
+	_, err = l.recordRewardRateUpdatedDetail(tx, ev)
+	return err
 }
 
-func (l *listenerService) ReplayERC20FromLast(ctx context.Context, contractAddress common.Address, starkBlock uint64, confirmations uint64) error {
-	key := syncKey(ERC20Prefix, contractAddress)
-	lastBlock, err := l.getSyncBlock(key)
-	if err != nil {
-		return err
+func (l *listenerService) handleOwnershipTransferred(tx *gorm.DB, ev *staking.StakingOwnershipTransferred) error {
+	if ev == nil {
+		return nil
 	}
-	if lastBlock == 0 && starkBlock > 0 {
-		lastBlock = starkBlock - 1
+	indexedMap := map[string]string{
+		"previous_owner": ev.PreviousOwner.Hex(),
+		"new_owner":      ev.NewOwner.Hex(),
 	}
-	latestHeader, err := l.client.HeaderByNumber(ctx, nil)
-	if err != nil {
+	ok, err := l.recordEventMap(tx, ev.Raw, "ownership_transferred", indexedMap)
+	if err != nil || !ok {
 		return err
 	}
-	latest := latestHeader.Number.Uint64()
-	if confirmations > 1 && latest >= confirmations-1 {
-		latest = latest - (confirmations - 1)
-	}
-	if lastBlock > latest {
-		return nil
-	}
-	return l.replayERC20Range(ctx, contractAddress, lastBlock+1, latest)
+	_, err = l.recordOwnershipTransferredDetail(tx, ev)
+	return err
 }
 
-func (l *listenerService) StartERC20ReplayLoop(ctx context.Context, contractAddress common.Address, starkBlock uint64, confirmations uint64, interval time.Duration) {
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		case <-ticker.C:
-			if err := l.ReplayERC20FromLast(ctx, contractAddress, starkBlock, confirmations); err != nil {
-				logger.WithModule("listener").WithError(err).Error("start erc20 replay loop failed")
-			}
-		}
+func (l *listenerService) handleErc20Transfer(tx *gorm.DB, ev *erc20.Erc20Transfer) error {
+	if ev == nil {
+		return nil
 	}
-}
-
-func (l *listenerService) replayERC20Range(ctx context.Context, contractAddress common.Address, start uint64, end uint64) error {
-	token, err := erc20.NewErc20(contractAddress, l.client)
-	if err != nil {
+	ok, err := l.recordErc20Transfer(tx, ev)
+	if err != nil || !ok {
 		return err
 	}
-	endCopy := end
-	if err := l.consumeErc20Transfer(token, ctx, start, &endCopy); err != nil {
-		logger.WithModule("listener").WithError(err).Error("replay erc20 transfer failed")
+	created, err := l.recordErc20TransferDetail(tx, ev)
+	if err != nil || !created {
 		return err
 	}
-	if err := l.consumeErc20Approval(token, ctx, start, &endCopy); err != nil {
-		logger.WithModule("listener").WithError(err).Error("replay erc20 approve failed")
+	if err := applyTransferBalance(tx, ev.Raw, ev.From, ev.To, ev.Value); err != nil {
 		return err
 	}
-	return l.setSyncBlock(syncKey(ERC20Prefix, contractAddress), end)
+	return applyTransferSpend(tx, ev.Raw, ev.From, ev.To, ev.Value)
 }
 
-func (l *listenerService) handleErc20Transfer(ev *erc20.Erc20Transfer) {
-	if ev == nil {
-		return
-	}
-	ok, err := l.recordErc20Transfer(ev)
-	if err != nil || !ok {
-		return
-	}
-	_, _ = l.recordErc20TransferDetail(ev)
-	_ = l.setSyncBlock(syncKey(ERC20Prefix, ev.Raw.Address), ev.Raw.BlockNumber)
-}
-
-func (l *listenerService) recordErc20Transfer(ev *erc20.Erc20Transfer) (bool, error) {
+func (l *listenerService) recordErc20Transfer(tx *gorm.DB, ev *erc20.Erc20Transfer) (bool, error) {
 	indexedMap := map[string]string{
 		"from":  ev.From.Hex(),
 		"to":    ev.To.Hex(),
 		"value": ev.Value.String(),
 	}
-	return l.recordEventMap(ev.Raw, ERC20Prefix, indexedMap)
+	return l.recordEventMap(tx, ev.Raw, ERC20Prefix, indexedMap)
 }
 
-func (l *listenerService) handleErc20Approval(ev *erc20.Erc20Approval) {
+func (l *listenerService) handleErc20Approval(tx *gorm.DB, ev *erc20.Erc20Approval) error {
 	if ev == nil {
-		return
+		return nil
 	}
-	ok, err := l.recordErc20Approval(ev)
+	ok, err := l.recordErc20Approval(tx, ev)
 	if err != nil || !ok {
-		return
+		return err
 	}
-	_, _ = l.recordErc20ApprovalDetail(ev)
-	_ = l.setSyncBlock(syncKey(ERC20Prefix, ev.Raw.Address), ev.Raw.BlockNumber)
+	created, err := l.recordErc20ApprovalDetail(tx, ev)
+	if err != nil || !created {
+		return err
+	}
+	return applyApproval(tx, ev.Raw, ev.Owner, ev.Spender, ev.Value)
 }
 
-func (l *listenerService) recordErc20Approval(ev *erc20.Erc20Approval) (bool, error) {
+func (l *listenerService) recordErc20Approval(tx *gorm.DB, ev *erc20.Erc20Approval) (bool, error) {
 	indexedMap := map[string]string{
 		"owner":   ev.Owner.Hex(),
 		"spender": ev.Spender.Hex(),
 		"value":   ev.Value.String(),
 	}
-	return l.recordEventMap(ev.Raw, ERC20Prefix, indexedMap)
+	return l.recordEventMap(tx, ev.Raw, ERC20Prefix, indexedMap)
 }
 
-func (l *listenerService) recordEvent(logEntry types.Log, eventName string) (bool, error) {
+func (l *listenerService) recordEvent(tx *gorm.DB, logEntry types.Log, eventName string) (bool, error) {
 	if len(logEntry.Topics) < 3 {
 		return false, nil
 	}
@@ -269,221 +570,243 @@ func (l *listenerService) recordEvent(logEntry types.Log, eventName string) (boo
 		"user":   common.BytesToAddress(logEntry.Topics[1].Bytes()[12:]).Hex(),
 		"amount": new(big.Int).SetBytes(logEntry.Topics[2].Bytes()).String(),
 	}
-	return l.recordEventMap(logEntry, eventName, indexedMap)
+	return l.recordEventMap(tx, logEntry, eventName, indexedMap)
+}
+
+func (l *listenerService) recordEventMap(tx *gorm.DB, logEntry types.Log, eventName string, indexedMap map[string]string) (bool, error) {
+	args := make(map[string]interface{}, len(indexedMap)+1)
+	for k, v := range indexedMap {
+		args[k] = v
+	}
+	return l.recordEventArgs(tx, logEntry, eventName, args)
 }
 
-func (l *listenerService) recordEventMap(logEntry types.Log, eventName string, indexedMap map[string]string) (bool, error) {
+func (l *listenerService) recordEventArgs(tx *gorm.DB, logEntry types.Log, eventName string, args map[string]interface{}) (bool, error) {
 	signature := ""
 	if len(logEntry.Topics) > 0 {
 		signature = logEntry.Topics[0].Hex()
 	}
-	indexedMap["signature"] = signature
-	marshal, err := json.Marshal(indexedMap)
+	args["signature"] = signature
+	marshal, err := json.Marshal(args)
 	if err != nil {
 		return false, err
 	}
 	entry := models.EventLog{
-		TxHash:      logEntry.TxHash.Hex(),
-		LogIndex:    logEntry.Index,
-		BlockNumber: logEntry.BlockNumber,
-		Event:       eventName,
-		EventArgs:   string(marshal),
-		Contract:    logEntry.Address.Hex(),
-	}
-	result := models.DB.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
+		TxHash:         logEntry.TxHash.Hex(),
+		LogIndex:       logEntry.Index,
+		BlockNumber:    logEntry.BlockNumber,
+		BlockTimestamp: logEntry.BlockTimestamp,
+		Event:          eventName,
+		EventArgs:      string(marshal),
+		Contract:       logEntry.Address.Hex(),
+	}
+	result := tx.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
 	if result.Error != nil {
 		return false, result.Error
 	}
 	return result.RowsAffected > 0, nil
 }
 
-func (l *listenerService) recordStakedDetail(ev *staking.StakingStaked) (bool, error) {
+func (l *listenerService) recordStakedDetail(tx *gorm.DB, ev *staking.StakingStaked) (bool, error) {
 	entry := models.StakingEventStaked{
-		TxHash:      ev.Raw.TxHash.Hex(),
-		LogIndex:    ev.Raw.Index,
-		BlockNumber: ev.Raw.BlockNumber,
-		Contract:    ev.Raw.Address.Hex(),
-		User:        ev.User.Hex(),
-		Amount:      ev.Amount.String(),
-	}
-	result := models.DB.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
+		TxHash:         ev.Raw.TxHash.Hex(),
+		LogIndex:       ev.Raw.Index,
+		BlockNumber:    ev.Raw.BlockNumber,
+		BlockTimestamp: ev.Raw.BlockTimestamp,
+		Contract:       ev.Raw.Address.Hex(),
+		User:           ev.User.Hex(),
+		Amount:         ev.Amount.String(),
+	}
+	result := tx.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
 	if result.Error != nil {
 		return false, result.Error
 	}
 	return result.RowsAffected > 0, nil
 }
 
-func (l *listenerService) recordWithdrawnDetail(ev *staking.StakingWithdrawn) (bool, error) {
+func (l *listenerService) recordWithdrawnDetail(tx *gorm.DB, ev *staking.StakingWithdrawn) (bool, error) {
 	entry := models.StakingEventWithdrawn{
-		TxHash:      ev.Raw.TxHash.Hex(),
-		LogIndex:    ev.Raw.Index,
-		BlockNumber: ev.Raw.BlockNumber,
-		Contract:    ev.Raw.Address.Hex(),
-		User:        ev.User.Hex(),
-		Amount:      ev.Amount.String(),
-	}
-	result := models.DB.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
+		TxHash:         ev.Raw.TxHash.Hex(),
+		LogIndex:       ev.Raw.Index,
+		BlockNumber:    ev.Raw.BlockNumber,
+		BlockTimestamp: ev.Raw.BlockTimestamp,
+		Contract:       ev.Raw.Address.Hex(),
+		User:           ev.User.Hex(),
+		Amount:         ev.Amount.String(),
+	}
+	result := tx.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
 	if result.Error != nil {
 		return false, result.Error
 	}
 	return result.RowsAffected > 0, nil
 }
 
-func (l *listenerService) recordRewardsClaimedDetail(ev *staking.StakingRewardsClaimed) (bool, error) {
+func (l *listenerService) recordRewardsClaimedDetail(tx *gorm.DB, ev *staking.StakingRewardsClaimed) (bool, error) {
 	entry := models.StakingEventRewardsClaimed{
-		TxHash:      ev.Raw.TxHash.Hex(),
-		LogIndex:    ev.Raw.Index,
-		BlockNumber: ev.Raw.BlockNumber,
-		Contract:    ev.Raw.Address.Hex(),
-		User:        ev.User.Hex(),
-		Amount:      ev.Amount.String(),
-	}
-	result := models.DB.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
+		TxHash:         ev.Raw.TxHash.Hex(),
+		LogIndex:       ev.Raw.Index,
+		BlockNumber:    ev.Raw.BlockNumber,
+		BlockTimestamp: ev.Raw.BlockTimestamp,
+		Contract:       ev.Raw.Address.Hex(),
+		User:           ev.User.Hex(),
+		Amount:         ev.Amount.String(),
+	}
+	result := tx.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
 	if result.Error != nil {
 		return false, result.Error
 	}
 	return result.RowsAffected > 0, nil
 }
 
-func (l *listenerService) recordRewardRateUpdatedDetail(ev *staking.StakingRewardRateUpdated) (bool, error) {
+func (l *listenerService) recordRewardRateUpdatedDetail(tx *gorm.DB, ev *staking.StakingRewardRateUpdated) (bool, error) {
 	entry := models.StakingEventRewardRateUpdated{
-		TxHash:        ev.Raw.TxHash.Hex(),
-		LogIndex:      ev.Raw.Index,
-		BlockNumber:   ev.Raw.BlockNumber,
-		Contract:      ev.Raw.Address.Hex(),
-		NewRewardRate: ev.NewRewardRate.String(),
+		TxHash:         ev.Raw.TxHash.Hex(),
+		LogIndex:       ev.Raw.Index,
+		BlockNumber:    ev.Raw.BlockNumber,
+		BlockTimestamp: ev.Raw.BlockTimestamp,
+		Contract:       ev.Raw.Address.Hex(),
+		NewRewardRate:  ev.NewRewardRate.String(),
+	}
+	result := tx.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (l *listenerService) recordOwnershipTransferredDetail(tx *gorm.DB, ev *staking.StakingOwnershipTransferred) (bool, error) {
+	entry := models.StakingEventOwnershipTransferred{
+		TxHash:         ev.Raw.TxHash.Hex(),
+		LogIndex:       ev.Raw.Index,
+		BlockNumber:    ev.Raw.BlockNumber,
+		BlockTimestamp: ev.Raw.BlockTimestamp,
+		Contract:       ev.Raw.Address.Hex(),
+		PreviousOwner:  ev.PreviousOwner.Hex(),
+		NewOwner:       ev.NewOwner.Hex(),
 	}
-	result := models.DB.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
+	result := tx.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
 	if result.Error != nil {
 		return false, result.Error
 	}
 	return result.RowsAffected > 0, nil
 }
 
-func (l *listenerService) recordErc20TransferDetail(ev *erc20.Erc20Transfer) (bool, error) {
+func (l *listenerService) recordErc20TransferDetail(tx *gorm.DB, ev *erc20.Erc20Transfer) (bool, error) {
 	entry := models.ERC20EventTransfer{
-		TxHash:      ev.Raw.TxHash.Hex(),
-		LogIndex:    ev.Raw.Index,
-		BlockNumber: ev.Raw.BlockNumber,
-		Contract:    ev.Raw.Address.Hex(),
-		From:        ev.From.Hex(),
-		To:          ev.To.Hex(),
-		Value:       ev.Value.String(),
-	}
-	result := models.DB.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
+		TxHash:         ev.Raw.TxHash.Hex(),
+		LogIndex:       ev.Raw.Index,
+		BlockNumber:    ev.Raw.BlockNumber,
+		BlockTimestamp: ev.Raw.BlockTimestamp,
+		Contract:       ev.Raw.Address.Hex(),
+		From:           ev.From.Hex(),
+		To:             ev.To.Hex(),
+		Value:          ev.Value.String(),
+	}
+	result := tx.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
 	if result.Error != nil {
 		return false, result.Error
 	}
 	return result.RowsAffected > 0, nil
 }
 
-func (l *listenerService) recordErc20ApprovalDetail(ev *erc20.Erc20Approval) (bool, error) {
+func (l *listenerService) recordErc20ApprovalDetail(tx *gorm.DB, ev *erc20.Erc20Approval) (bool, error) {
 	entry := models.ERC20EventApproval{
-		TxHash:      ev.Raw.TxHash.Hex(),
-		LogIndex:    ev.Raw.Index,
-		BlockNumber: ev.Raw.BlockNumber,
-		Contract:    ev.Raw.Address.Hex(),
-		Owner:       ev.Owner.Hex(),
-		Spender:     ev.Spender.Hex(),
-		Value:       ev.Value.String(),
-	}
-	result := models.DB.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
+		TxHash:         ev.Raw.TxHash.Hex(),
+		LogIndex:       ev.Raw.Index,
+		BlockNumber:    ev.Raw.BlockNumber,
+		BlockTimestamp: ev.Raw.BlockTimestamp,
+		Contract:       ev.Raw.Address.Hex(),
+		Owner:          ev.Owner.Hex(),
+		Spender:        ev.Spender.Hex(),
+		Value:          ev.Value.String(),
+	}
+	result := tx.Where("tx_hash=? and log_index=?", entry.TxHash, entry.LogIndex).FirstOrCreate(&entry)
 	if result.Error != nil {
 		return false, result.Error
 	}
 	return result.RowsAffected > 0, nil
 }
 
-func (l *listenerService) consumeStakingStaked(s *staking.Staking, ctx context.Context, start uint64, end *uint64) error {
-	iter, err := s.FilterStaked(&bind.FilterOpts{Start: start, End: end, Context: ctx}, nil, nil)
+// setSyncBlock 检查点推进到 blocks 的最后一个区块, 并记录 blocks 的哈希
+func (l *listenerService) setSyncBlock(tx *gorm.DB, key string, blocks []blockHash) error {
+	last := blocks[len(blocks)-1]
+	state := models.SyncState{Name: key, BlockNumber: last.number, BlockHash: last.hash.Hex()}
+	err := tx.Where("name=?", key).Assign(models.SyncState{BlockNumber: last.number, BlockHash: last.hash.Hex()}).FirstOrCreate(&state).Error
 	if err != nil {
 		return err
 	}
-	defer iter.Close()
-	for iter.Next() {
-		l.handleStaked(iter.Event)
-	}
-	return nil
+	return l.recordSyncBlocks(tx, key, blocks)
 }
 
-func (l *listenerService) consumeStakingWithdrawn(s *staking.Staking, ctx context.Context, start uint64, end *uint64) error {
-	iter, err := s.FilterWithdrawn(&bind.FilterOpts{Start: start, End: end, Context: ctx}, nil, nil)
-	if err != nil {
-		return err
-	}
-	defer iter.Close()
-	for iter.Next() {
-		l.handleWithdrawn(iter.Event)
+func (l *listenerService) getSyncBlock(key string) (uint64, error) {
+	state, err := l.getSyncState(key)
+	if err != nil || state == nil {
+		return 0, err
 	}
-	return nil
+	return state.BlockNumber, nil
 }
 
-func (l *listenerService) consumeStakingRewardsClaimed(s *staking.Staking, ctx context.Context, start uint64, end *uint64) error {
-	iter, err := s.FilterRewardsClaimed(&bind.FilterOpts{Start: start, End: end, Context: ctx}, nil, nil)
-	if err != nil {
-		return err
+func (l *listenerService) getSyncState(key string) (*models.SyncState, error) {
+	var state models.SyncState
+	err := models.DB.Where("name = ?", key).First(&state).Error
+	if err == nil {
+		return &state, nil
 	}
-	defer iter.Close()
-	for iter.Next() {
-		l.handleRewardsClaimed(iter.Event)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
 	}
-	return nil
+	return nil, err
 }
 
-func (l *listenerService) consumeStakingRewardRateUpdated(s *staking.Staking, ctx context.Context, start uint64, end *uint64) error {
-	iter, err := s.FilterRewardRateUpdated(&bind.FilterOpts{Start: start, End: end, Context: ctx})
-	if err != nil {
-		return err
-	}
-	defer iter.Close()
-	for iter.Next() {
-		l.handleRewardRateUpdated(iter.Event)
-	}
-	return nil
+// 区块地址
+func syncKey(prefix string, contractAddress common.Address) string {
+	return prefix + strings.ToLower(contractAddress.Hex())
 }
 
-func (l *listenerService) consumeErc20Transfer(token *erc20.Erc20, ctx context.Context, start uint64, end *uint64) error {
-	iter, err := token.FilterTransfer(&bind.FilterOpts{Start: start, End: end, Context: ctx}, nil, nil)
-	if err != nil {
-		return err
+func (t WatchTarget) syncKey() string {
+	switch t.Kind {
+	case ContractKindERC20:
+		return syncKey(ERC20Prefix, t.Address)
+	case ContractKindABI:
+		return syncKey(ABIPrefix, t.Address)
 	}
-	defer iter.Close()
-	for iter.Next() {
-		l.handleErc20Transfer(iter.Event)
-	}
-	return nil
+	return syncKey(StakingPrefix, t.Address)
 }
 
-func (l *listenerService) consumeErc20Approval(token *erc20.Erc20, ctx context.Context, start uint64, end *uint64) error {
-	iter, err := token.FilterApproval(&bind.FilterOpts{Start: start, End: end, Context: ctx}, nil, nil)
-	if err != nil {
-		return err
-	}
-	defer iter.Close()
-	for iter.Next() {
-		l.handleErc20Approval(iter.Event)
+// eventModels 重组回滚时需要清理的明细表, 通用合约只有 event_log
+func (t WatchTarget) eventModels() []interface{} {
+	switch t.Kind {
+	case ContractKindERC20:
+		return erc20EventModels
+	case ContractKindABI:
+		return nil
 	}
-	return nil
+	return stakingEventModels
 }
 
-func (l *listenerService) setSyncBlock(key string, block uint64) error {
-	state := models.SyncState{Name: key, BlockNumber: block}
-	return models.DB.Where("name=?", key).Assign(models.SyncState{BlockNumber: block}).FirstOrCreate(&state).Error
-}
+var (
+	stakingABI = mustParseABI(staking.StakingMetaData)
+	erc20ABI   = mustParseABI(erc20.Erc20MetaData)
+)
 
-func (l *listenerService) getSyncBlock(key string) (uint64, error) {
-	var state models.SyncState
-	err := models.DB.Where("name = ?", key).First(&state).Error
-	if err == nil {
-		return state.BlockNumber, nil
-	}
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return 0, nil
+// 需要索引的事件
+var (
+	stakingEvents = []string{"Staked", "Withdrawn", "RewardsClaimed", "RewardRateUpdated", "OwnershipTransferred"}
+	erc20Events   = []string{"Transfer", "Approval"}
+)
+
+func eventIDs(parsed *abi.ABI, names []string) []common.Hash {
+	topics := make([]common.Hash, 0, len(names))
+	for _, name := range names {
+		topics = append(topics, parsed.Events[name].ID)
 	}
-	return 0, err
+	return topics
 }
 
-// 区块地址
-func syncKey(prefix string, contractAddress common.Address) string {
-	return prefix + strings.ToLower(contractAddress.Hex())
+func mustParseABI(metaData *bind.MetaData) *abi.ABI {
+	parsed, err := metaData.GetAbi()
+	if err != nil {
+		panic(err)
+	}
+	return parsed
 }
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"go-solidity-staking/accrual"
+	"go-solidity-staking/gen/staking"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+type MerkleService interface {
+	Generate(ctx context.Context, contractAddress common.Address, blockNumber *uint64, verify bool) (*models.MerkleSnapshot, error)
+	List(ctx context.Context, contractAddress *common.Address, pageNum int, pageSize int) ([]models.MerkleSnapshot, int64, error)
+	Leaves(ctx context.Context, snapshotID uint, pageNum int, pageSize int) ([]models.MerkleSnapshotLeaf, int64, error)
+	Proof(ctx context.Context, snapshotID uint, account common.Address) (*MerkleProof, error)
+}
+
+// MerkleProof 叶子与从叶子到根的兄弟节点, 可直接用于 OpenZeppelin MerkleProof.verify
+type MerkleProof struct {
+	SnapshotID  uint     `json:"snapshotId"`
+	Contract    string   `json:"contract"`
+	BlockNumber uint64   `json:"blockNumber"`
+	Root        string   `json:"root"`
+	Account     string   `json:"account"`
+	Amount      string   `json:"amount"`
+	LeafIndex   int      `json:"leafIndex"`
+	LeafHash    string   `json:"leafHash"`
+	Proof       []string `json:"proof"`
+}
+
+type merkleService struct {
+	client *ethclient.Client
+	mu     sync.Mutex
+	trees  map[uint][][]common.Hash // 快照生成后不再变化, 按快照缓存各层节点
+}
+
+func NewMerkleService(client *ethclient.Client) MerkleService {
+	return &merkleService{client: client, trees: make(map[uint][][]common.Hash)}
+}
+
+// Generate 按截至 blockNumber 的已索引事件计算 staking_position 中每个用户的 stakedBalance,
+// verify 时逐个与该区块的链上调用核对, 不一致则不生成; blockNumber 为空时取已索引的最新区块
+func (m *merkleService) Generate(ctx context.Context, contractAddress common.Address, blockNumber *uint64, verify bool) (*models.MerkleSnapshot, error) {
+	contract := contractAddress.Hex()
+	var state models.SyncState
+	err := models.DB.WithContext(ctx).Where("name = ?", WatchTarget{Kind: ContractKindStaking, Address: contractAddress}.syncKey()).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("contract %s has not been indexed", contract)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load sync state: %w", err)
+	}
+	height := state.BlockNumber
+	if blockNumber != nil {
+		if *blockNumber > state.BlockNumber {
+			return nil, fmt.Errorf("block %d is beyond indexed block %d", *blockNumber, state.BlockNumber)
+		}
+		height = *blockNumber
+	}
+	block := BlockRef{Number: new(big.Int).SetUint64(height)}
+	header, err := m.client.HeaderByNumber(ctx, block.Number)
+	if err != nil {
+		return nil, fmt.Errorf("get header %d: %w", height, err)
+	}
+
+	var users []string
+	if err := models.DB.WithContext(ctx).Model(&models.StakingPosition{}).Where("contract = ?", contract).Pluck("user", &users).Error; err != nil {
+		return nil, fmt.Errorf("load stakers: %w", err)
+	}
+	events, err := loadAccrualEvents(ctx, contract, "block_number <= ?", height)
+	if err != nil {
+		return nil, err
+	}
+	engine := accrual.NewEngine(accrual.DefaultRewardRate)
+	for _, ev := range events {
+		if err := engine.Apply(ev); err != nil {
+			return nil, fmt.Errorf("replay accrual: %w", err)
+		}
+	}
+
+	var newStaking *staking.Staking
+	if verify {
+		newStaking, err = staking.NewStaking(contractAddress, m.client)
+		if err != nil {
+			return nil, fmt.Errorf("new staking contract: %w", err)
+		}
+	}
+	type entry struct {
+		leaf    models.MerkleSnapshotLeaf
+		hash    common.Hash
+		balance *big.Int
+	}
+	var entries []entry
+	mismatched := 0
+	for _, user := range users {
+		balance := engine.User(user).StakedBalance
+		if verify {
+			onchain, err := newStaking.StakedBalance(block.callOpts(ctx), common.HexToAddress(user))
+			if err != nil {
+				return nil, fmt.Errorf("stakedBalance call: %w", block.callErr(err))
+			}
+			if onchain.Cmp(balance) != 0 {
+				mismatched++
+				logger.WithModule("merkle").WithFields(logrus.Fields{
+					"contract": contract,
+					"block":    height,
+					"account":  user,
+					"indexed":  balance.String(),
+					"onchain":  onchain.String(),
+				}).Warn("staked balance mismatch")
+				continue
+			}
+		}
+		if balance.Sign() == 0 {
+			continue
+		}
+		hash := merkleLeaf(common.HexToAddress(user), balance)
+		entries = append(entries, entry{
+			leaf:    models.MerkleSnapshotLeaf{Account: user, Amount: balance.String(), LeafHash: hash.Hex()},
+			hash:    hash,
+			balance: balance,
+		})
+	}
+	if mismatched > 0 {
+		return nil, fmt.Errorf("%d of %d stakers differ from on-chain stakedBalance at block %d, reconcile or reindex first", mismatched, len(users), height)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("no stakers at block %d", height)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].hash[:], entries[j].hash[:]) < 0
+	})
+	hashes := make([]common.Hash, len(entries))
+	leaves := make([]models.MerkleSnapshotLeaf, len(entries))
+	total := new(big.Int)
+	for i, e := range entries {
+		hashes[i] = e.hash
+		leaves[i] = e.leaf
+		leaves[i].LeafIndex = i
+		total.Add(total, e.balance)
+	}
+	layers := merkleLayers(hashes)
+	snapshot := &models.MerkleSnapshot{
+		Contract:    contract,
+		BlockNumber: height,
+		BlockTime:   header.Time,
+		Root:        layers[len(layers)-1][0].Hex(),
+		LeafCount:   len(leaves),
+		TotalStaked: total.String(),
+		Verified:    verify,
+		CreatedAt:   time.Now(),
+	}
+	err = models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(snapshot).Error; err != nil {
+			return err
+		}
+		for i := range leaves {
+			leaves[i].SnapshotID = snapshot.ID
+		}
+		return tx.CreateInBatches(leaves, 500).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save snapshot: %w", err)
+	}
+	m.mu.Lock()
+	m.trees[snapshot.ID] = layers
+	m.mu.Unlock()
+	logger.WithModule("merkle").WithFields(logrus.Fields{
+		"contract": contract,
+		"block":    height,
+		"id":       snapshot.ID,
+		"root":     snapshot.Root,
+		"leaves":   snapshot.LeafCount,
+		"verified": verify,
+	}).Info("merkle snapshot generated")
+	return snapshot, nil
+}
+
+func (m *merkleService) List(ctx context.Context, contractAddress *common.Address, pageNum int, pageSize int) ([]models.MerkleSnapshot, int64, error) {
+	query := models.DB.WithContext(ctx).Model(&models.MerkleSnapshot{})
+	if contractAddress != nil {
+		query = query.Where("contract = ?", contractAddress.Hex())
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count snapshots: %w", err)
+	}
+	var list []models.MerkleSnapshot
+	err := query.Order("id desc").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list snapshots: %w", err)
+	}
+	return list, total, nil
+}
+
+func (m *merkleService) Leaves(ctx context.Context, snapshotID uint, pageNum int, pageSize int) ([]models.MerkleSnapshotLeaf, int64, error) {
+	query := models.DB.WithContext(ctx).Model(&models.MerkleSnapshotLeaf{}).Where("snapshot_id = ?", snapshotID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count leaves: %w", err)
+	}
+	var list []models.MerkleSnapshotLeaf
+	err := query.Order("leaf_index").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list leaves: %w", err)
+	}
+	return list, total, nil
+}
+
+// Proof 地址不在快照中 (该区块没有质押) 时返回错误
+func (m *merkleService) Proof(ctx context.Context, snapshotID uint, account common.Address) (*MerkleProof, error) {
+	var snapshot models.MerkleSnapshot
+	err := models.DB.WithContext(ctx).First(&snapshot, snapshotID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("snapshot %d not found", snapshotID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get snapshot: %w", err)
+	}
+	var leaf models.MerkleSnapshotLeaf
+	err = models.DB.WithContext(ctx).Where("snapshot_id = ? and account = ?", snapshotID, account.Hex()).First(&leaf).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("account %s is not in snapshot %d", account.Hex(), snapshotID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get leaf: %w", err)
+	}
+	layers, err := m.tree(ctx, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	proof := []string{}
+	for _, hash := range merkleProof(layers, leaf.LeafIndex) {
+		proof = append(proof, hash.Hex())
+	}
+	return &MerkleProof{
+		SnapshotID:  snapshot.ID,
+		Contract:    snapshot.Contract,
+		BlockNumber: snapshot.BlockNumber,
+		Root:        snapshot.Root,
+		Account:     leaf.Account,
+		Amount:      leaf.Amount,
+		LeafIndex:   leaf.LeafIndex,
+		LeafHash:    leaf.LeafHash,
+		Proof:       proof,
+	}, nil
+}
+
+// tree 按叶子重建各层节点并校验根
+func (m *merkleService) tree(ctx context.Context, snapshot models.MerkleSnapshot) ([][]common.Hash, error) {
+	m.mu.Lock()
+	layers, ok := m.trees[snapshot.ID]
+	m.mu.Unlock()
+	if ok {
+		return layers, nil
+	}
+	var leafHashes []string
+	err := models.DB.WithContext(ctx).Model(&models.MerkleSnapshotLeaf{}).
+		Where("snapshot_id = ?", snapshot.ID).Order("leaf_index").
+		Pluck("leaf_hash", &leafHashes).Error
+	if err != nil {
+		return nil, fmt.Errorf("load leaves: %w", err)
+	}
+	if len(leafHashes) != snapshot.LeafCount {
+		return nil, fmt.Errorf("snapshot %d has %d leaves, expected %d", snapshot.ID, len(leafHashes), snapshot.LeafCount)
+	}
+	hashes := make([]common.Hash, len(leafHashes))
+	for i, hash := range leafHashes {
+		hashes[i] = common.HexToHash(hash)
+	}
+	layers = merkleLayers(hashes)
+	if root := layers[len(layers)-1][0].Hex(); root != snapshot.Root {
+		return nil, fmt.Errorf("snapshot %d leaves rebuild root %s, expected %s", snapshot.ID, root, snapshot.Root)
+	}
+	m.mu.Lock()
+	m.trees[snapshot.ID] = layers
+	m.mu.Unlock()
+	return layers, nil
+}
+
+// merkleLeaf 与 OpenZeppelin StandardMerkleTree 相同的双重哈希叶子, 防止第二原像攻击
+func merkleLeaf(account common.Address, amount *big.Int) common.Hash {
+	encoded := append(common.LeftPadBytes(account.Bytes(), 32), common.LeftPadBytes(amount.Bytes(), 32)...)
+	return crypto.Keccak256Hash(crypto.Keccak256(encoded))
+}
+
+// hashPair 两个节点按字节序排序后拼接哈希, 验证时不需要左右位置
+func hashPair(a common.Hash, b common.Hash) common.Hash {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return crypto.Keccak256Hash(a[:], b[:])
+}
+
+// merkleLayers 自底向上逐层两两哈希, 奇数个时最后一个节点直接进入上一层; 最后一层为根
+func merkleLayers(leaves []common.Hash) [][]common.Hash {
+	layers := [][]common.Hash{leaves}
+	for current := leaves; len(current) > 1; {
+		next := make([]common.Hash, 0, (len(current)+1)/2)
+		for i := 0; i < len(current); i += 2 {
+			if i+1 == len(current) {
+				next = append(next, current[i])
+			} else {
+				next = append(next, hashPair(current[i], current[i+1]))
+			}
+		}
+		layers = append(layers, next)
+		current = next
+	}
+	return layers
+}
+
+// merkleProof 叶子到根路径上每层的兄弟节点, 没有兄弟的层跳过
+func merkleProof(layers [][]common.Hash, index int) []common.Hash {
+	proof := []common.Hash{}
+	for _, layer := range layers[:len(layers)-1] {
+		sibling := index ^ 1
+		if sibling < len(layer) {
+			proof = append(proof, layer[sibling])
+		}
+		index /= 2
+	}
+	return proof
+}
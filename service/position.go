@@ -0,0 +1,189 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-solidity-staking/models"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 列表允许的排序字段
+var positionSortColumns = map[string]string{
+	"currentStaked":  "current_staked",
+	"totalStaked":    "total_staked",
+	"totalWithdrawn": "total_withdrawn",
+	"totalClaimed":   "total_claimed",
+	"firstBlock":     "first_block",
+	"lastBlock":      "last_block",
+}
+
+type PositionService interface {
+	List(ctx context.Context, contract common.Address, sortBy string, order string, pageNum int, pageSize int) ([]models.StakingPosition, int64, error)
+	Get(ctx context.Context, contract common.Address, user common.Address) (*models.StakingPosition, error)
+	Rebuild(ctx context.Context, contract common.Address) error
+}
+
+type positionService struct{}
+
+func NewPositionService() PositionService {
+	return &positionService{}
+}
+
+func (p *positionService) List(ctx context.Context, contract common.Address, sortBy string, order string, pageNum int, pageSize int) ([]models.StakingPosition, int64, error) {
+	column, ok := positionSortColumns[sortBy]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported sort field %q", sortBy)
+	}
+	if order != "asc" && order != "desc" {
+		return nil, 0, fmt.Errorf("unsupported sort order %q", order)
+	}
+	query := models.DB.WithContext(ctx).Model(&models.StakingPosition{}).Where("contract = ?", contract.Hex())
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count positions: %w", err)
+	}
+	var list []models.StakingPosition
+	err := query.Order(column + " " + order).Order("id").
+		Offset((pageNum - 1) * pageSize).
+		Limit(pageSize).
+		Find(&list).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list positions: %w", err)
+	}
+	return list, total, nil
+}
+
+func (p *positionService) Get(ctx context.Context, contract common.Address, user common.Address) (*models.StakingPosition, error) {
+	var position models.StakingPosition
+	err := models.DB.WithContext(ctx).Where("contract = ? and user = ?", contract.Hex(), user.Hex()).First(&position).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("no position for %s", user.Hex())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get position: %w", err)
+	}
+	return &position, nil
+}
+
+// Rebuild 按明细表重算合约的全部仓位, 用于已有数据初始化或修复
+func (p *positionService) Rebuild(ctx context.Context, contract common.Address) error {
+	return models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return rebuildPositions(tx, contract.Hex(), nil)
+	})
+}
+
+// applyPosition 新写入一条质押明细后增量更新仓位, 只在明细首次入库时调用以保证幂等
+func applyPosition(tx *gorm.DB, logEntry types.Log, user common.Address, staked *big.Int, withdrawn *big.Int, claimed *big.Int) error {
+	position := models.StakingPosition{
+		Contract:       logEntry.Address.Hex(),
+		User:           user.Hex(),
+		CurrentStaked:  new(big.Int).Sub(staked, withdrawn).String(),
+		TotalStaked:    staked.String(),
+		TotalWithdrawn: withdrawn.String(),
+		TotalClaimed:   claimed.String(),
+		FirstBlock:     logEntry.BlockNumber,
+		LastBlock:      logEntry.BlockNumber,
+		UpdatedAt:      time.Now(),
+	}
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"current_staked":  gorm.Expr("current_staked + VALUES(current_staked)"),
+			"total_staked":    gorm.Expr("total_staked + VALUES(total_staked)"),
+			"total_withdrawn": gorm.Expr("total_withdrawn + VALUES(total_withdrawn)"),
+			"total_claimed":   gorm.Expr("total_claimed + VALUES(total_claimed)"),
+			"first_block":     gorm.Expr("LEAST(first_block, VALUES(first_block))"),
+			"last_block":      gorm.Expr("GREATEST(last_block, VALUES(last_block))"),
+			"updated_at":      gorm.Expr("VALUES(updated_at)"),
+		}),
+	}).Create(&position).Error
+}
+
+// stakingAffected 将被删除的质押明细涉及的用户与最早区块时间
+type stakingAffected struct {
+	contract string
+	users    []string
+	since    uint64 // 0 表示没有受影响的明细
+}
+
+// collectStakingAffected 删除质押明细前调用, 删除后用 refresh 重算仓位并标记时间序列需要重建
+func collectStakingAffected(tx *gorm.DB, contract string, where string, args ...interface{}) (*stakingAffected, error) {
+	affected := &stakingAffected{contract: contract, users: []string{}}
+	seen := make(map[string]bool)
+	for _, model := range []interface{}{
+		&models.StakingEventStaked{},
+		&models.StakingEventWithdrawn{},
+		&models.StakingEventRewardsClaimed{},
+	} {
+		var rows []struct {
+			User           string
+			BlockTimestamp uint64
+		}
+		err := tx.Model(model).Select("user, MIN(block_timestamp) AS block_timestamp").
+			Where("contract = ?", contract).Where(where, args...).
+			Group("user").Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			if !seen[row.User] {
+				seen[row.User] = true
+				affected.users = append(affected.users, row.User)
+			}
+			if affected.since == 0 || row.BlockTimestamp < affected.since {
+				affected.since = row.BlockTimestamp
+			}
+		}
+	}
+	return affected, nil
+}
+
+func (a *stakingAffected) refresh(tx *gorm.DB) error {
+	if len(a.users) == 0 {
+		return nil
+	}
+	if err := rebuildPositions(tx, a.contract, a.users); err != nil {
+		return err
+	}
+	return markRollupDirty(tx, a.contract, a.since)
+}
+
+// rebuildPositions 按明细表重算仓位; users 为 nil 时重算合约的全部用户
+func rebuildPositions(tx *gorm.DB, contract string, users []string) error {
+	if users != nil && len(users) == 0 {
+		return nil
+	}
+	scope := "contract = ?"
+	args := []interface{}{contract}
+	if users != nil {
+		scope += " and user IN ?"
+		args = append(args, users)
+	}
+	if err := tx.Where(scope, args...).Delete(&models.StakingPosition{}).Error; err != nil {
+		return err
+	}
+	var unionArgs []interface{}
+	for i := 0; i < 3; i++ {
+		unionArgs = append(unionArgs, args...)
+	}
+	return tx.Exec(`INSERT INTO staking_position
+  (contract, user, current_staked, total_staked, total_withdrawn, total_claimed, first_block, last_block, updated_at)
+SELECT contract, user, SUM(staked) - SUM(withdrawn), SUM(staked), SUM(withdrawn), SUM(claimed), MIN(block_number), MAX(block_number), NOW()
+FROM (
+  SELECT contract, user, CAST(amount AS DECIMAL(65,0)) AS staked, 0 AS withdrawn, 0 AS claimed, block_number
+  FROM staking_event_staked WHERE `+scope+`
+  UNION ALL
+  SELECT contract, user, 0, CAST(amount AS DECIMAL(65,0)), 0, block_number
+  FROM staking_event_withdrawn WHERE `+scope+`
+  UNION ALL
+  SELECT contract, user, 0, 0, CAST(amount AS DECIMAL(65,0)), block_number
+  FROM staking_event_rewards_claimed WHERE `+scope+`
+) t
+GROUP BY contract, user`, unionArgs...).Error
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-solidity-staking/gen/oracle"
+	"math/big"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	PriceSourceStatic = "static"
+	PriceSourceOracle = "oracle"
+	PriceSourceHTTP   = "http"
+)
+
+// PriceSource 代币单价 (每个完整代币, 非最小单位)
+type PriceSource interface {
+	Price(ctx context.Context, token common.Address) (*big.Float, error)
+}
+
+type staticPriceSource struct {
+	prices map[common.Address]*big.Float
+}
+
+// NewStaticPriceSource 使用配置中的固定价格
+func NewStaticPriceSource(prices map[common.Address]*big.Float) PriceSource {
+	return &staticPriceSource{prices: prices}
+}
+
+func (s *staticPriceSource) Price(ctx context.Context, token common.Address) (*big.Float, error) {
+	price, ok := s.prices[token]
+	if !ok {
+		return nil, fmt.Errorf("no static price for token %s", token.Hex())
+	}
+	return new(big.Float).Set(price), nil
+}
+
+type oraclePriceSource struct {
+	client *ethclient.Client
+	feeds  map[common.Address]common.Address
+	maxAge time.Duration
+}
+
+// NewOraclePriceSource 读取 Chainlink 风格喂价合约, feeds 为 代币地址 -> 喂价合约地址;
+// maxAge 大于 0 时拒绝超过该时长未更新的价格
+func NewOraclePriceSource(client *ethclient.Client, feeds map[common.Address]common.Address, maxAge time.Duration) PriceSource {
+	return &oraclePriceSource{client: client, feeds: feeds, maxAge: maxAge}
+}
+
+func (o *oraclePriceSource) Price(ctx context.Context, token common.Address) (*big.Float, error) {
+	feed, ok := o.feeds[token]
+	if !ok {
+		return nil, fmt.Errorf("no price feed for token %s", token.Hex())
+	}
+	aggregator, err := oracle.NewOracle(feed, o.client)
+	if err != nil {
+		return nil, fmt.Errorf("new oracle contract: %w", err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	decimals, err := aggregator.Decimals(opts)
+	if err != nil {
+		return nil, fmt.Errorf("feed decimals call: %w", err)
+	}
+	round, err := aggregator.LatestRoundData(opts)
+	if err != nil {
+		return nil, fmt.Errorf("feed latestRoundData call: %w", err)
+	}
+	if round.Answer.Sign() <= 0 {
+		return nil, fmt.Errorf("feed %s returned invalid answer %s", feed.Hex(), round.Answer)
+	}
+	if o.maxAge > 0 && time.Since(time.Unix(round.UpdatedAt.Int64(), 0)) > o.maxAge {
+		return nil, fmt.Errorf("feed %s price is stale, updated at %s", feed.Hex(), round.UpdatedAt)
+	}
+	return scaleDown(round.Answer, decimals), nil
+}
+
+type httpPriceSource struct {
+	url    string
+	client *http.Client
+}
+
+// NewHTTPPriceSource 请求外部价格接口, url 中的 {token} 替换为代币地址,
+// 响应为 {"price": "1.23"} (字符串或数字); 测试时可指向本地 stub (deploy/pricestub)
+func NewHTTPPriceSource(url string, timeout time.Duration) PriceSource {
+	return &httpPriceSource{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+func (h *httpPriceSource) Price(ctx context.Context, token common.Address) (*big.Float, error) {
+	url := strings.ReplaceAll(h.url, "{token}", token.Hex())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("new price request: %w", err)
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("price request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("price request for %s: status %d", token.Hex(), resp.StatusCode)
+	}
+	var body struct {
+		Price json.Number `json:"price"`
+	}
+	decoder := json.NewDecoder(resp.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		return nil, fmt.Errorf("decode price response: %w", err)
+	}
+	price, ok := new(big.Float).SetString(body.Price.String())
+	if !ok || price.Sign() <= 0 {
+		return nil, fmt.Errorf("invalid price %q for %s", body.Price, token.Hex())
+	}
+	return price, nil
+}
+
+// scaleDown 最小单位转换为完整单位: value / 10^decimals
+func scaleDown(value *big.Int, decimals uint8) *big.Float {
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	return new(big.Float).Quo(new(big.Float).SetInt(value), new(big.Float).SetInt(unit))
+}
@@ -0,0 +1,38 @@
+package service
+
+import "gorm.io/gorm"
+
+// affectedProjections 将被删除的明细影响到的投影: 质押仓位/时间序列、持有人余额与授权额度
+type affectedProjections struct {
+	contract string
+	staking  *stakingAffected
+	holders  []string
+	owners   []string
+}
+
+// collectAffected 删除明细前调用, 删除后用 refresh 按剩余明细重算
+func collectAffected(tx *gorm.DB, contract string, where string, args ...interface{}) (*affectedProjections, error) {
+	staking, err := collectStakingAffected(tx, contract, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	holders, err := collectHolderAffected(tx, contract, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	owners, err := collectAllowanceAffected(tx, contract, where, args...)
+	if err != nil {
+		return nil, err
+	}
+	return &affectedProjections{contract: contract, staking: staking, holders: holders, owners: owners}, nil
+}
+
+func (a *affectedProjections) refresh(tx *gorm.DB) error {
+	if err := a.staking.refresh(tx); err != nil {
+		return err
+	}
+	if err := rebuildHolderBalances(tx, a.contract, a.holders); err != nil {
+		return err
+	}
+	return rebuildAllowances(tx, a.contract, a.owners)
+}
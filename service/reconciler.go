@@ -0,0 +1,374 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-solidity-staking/accrual"
+	"go-solidity-staking/gen/erc20"
+	"go-solidity-staking/gen/staking"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReconcilerService interface {
+	Run(ctx context.Context, contractAddress common.Address) (*ReconcileReport, error)
+	List(ctx context.Context, contractAddress *common.Address, status string, pageNum int, pageSize int) ([]models.ReconcileDiscrepancy, int64, error)
+	Reindex(ctx context.Context, id uint, fromBlock *uint64) (*models.ReconcileDiscrepancy, error)
+	StartLoop(ctx context.Context, interval time.Duration)
+}
+
+// ReconcileReport 一次对账的结果
+type ReconcileReport struct {
+	Contract    string `json:"contract"`
+	Kind        string `json:"kind"`
+	BlockNumber uint64 `json:"blockNumber"`
+	Accounts    int    `json:"accounts"`
+	Mismatched  int    `json:"mismatched"`
+	Resolved    int64  `json:"resolved"`
+}
+
+type reconcilerService struct {
+	client     *ethclient.Client
+	registry   RegistryService
+	sampleSize int
+}
+
+// NewReconcilerService sampleSize 为每轮每个合约随机抽查的账户数, 0 为全量
+func NewReconcilerService(client *ethclient.Client, registry RegistryService, sampleSize int) ReconcilerService {
+	return &reconcilerService{client: client, registry: registry, sampleSize: sampleSize}
+}
+
+// reconcileCheck 一个账户一个字段的对比结果
+type reconcileCheck struct {
+	account string
+	field   string
+	indexed *big.Int
+	onchain *big.Int
+}
+
+// Run 在合约已确认入库的区块上对比已索引状态与链上调用结果
+func (r *reconcilerService) Run(ctx context.Context, contractAddress common.Address) (*ReconcileReport, error) {
+	var contract models.WatchedContract
+	err := models.DB.WithContext(ctx).Where("address = ?", contractAddress.Hex()).First(&contract).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("contract %s not registered", contractAddress.Hex())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load contract: %w", err)
+	}
+	target := contractTarget(contract)
+	report := &ReconcileReport{Contract: contract.Address, Kind: contract.Kind}
+	var state models.SyncState
+	err = models.DB.WithContext(ctx).Where("name = ?", target.syncKey()).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return report, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load sync state: %w", err)
+	}
+	report.BlockNumber = state.BlockNumber
+	block := BlockRef{Number: new(big.Int).SetUint64(state.BlockNumber)}
+
+	var checks []reconcileCheck
+	switch contract.Kind {
+	case ContractKindStaking:
+		checks, err = r.checkStaking(ctx, target.Address, block)
+	case ContractKindERC20:
+		checks, err = r.checkErc20(ctx, target.Address, block)
+	default:
+		return nil, fmt.Errorf("contract kind %s does not support reconciliation", contract.Kind)
+	}
+	if err != nil {
+		return nil, err
+	}
+	accounts := make(map[string]bool)
+	for _, check := range checks {
+		accounts[check.account] = true
+		if check.indexed.Cmp(check.onchain) == 0 {
+			result := models.DB.WithContext(ctx).Model(&models.ReconcileDiscrepancy{}).
+				Where("contract = ? and account = ? and field = ? and status <> ?", contract.Address, check.account, check.field, models.DiscrepancyResolved).
+				Update("status", models.DiscrepancyResolved)
+			if result.Error != nil {
+				return nil, fmt.Errorf("resolve discrepancy: %w", result.Error)
+			}
+			report.Resolved += result.RowsAffected
+			continue
+		}
+		report.Mismatched++
+		if err := recordDiscrepancy(ctx, contract, check, state.BlockNumber); err != nil {
+			return nil, err
+		}
+	}
+	report.Accounts = len(accounts)
+	logger.WithModule("reconciler").WithFields(logrus.Fields{
+		"contract":   report.Contract,
+		"block":      report.BlockNumber,
+		"accounts":   report.Accounts,
+		"mismatched": report.Mismatched,
+		"resolved":   report.Resolved,
+	}).Info("reconcile finished")
+	return report, nil
+}
+
+// recordDiscrepancy 同一账户同一字段只保留一条 open 差异, 再次不一致时刷新区块与两侧的值
+func recordDiscrepancy(ctx context.Context, contract models.WatchedContract, check reconcileCheck, blockNumber uint64) error {
+	values := map[string]interface{}{
+		"block_number":  blockNumber,
+		"indexed_value": check.indexed.String(),
+		"onchain_value": check.onchain.String(),
+	}
+	var open []models.ReconcileDiscrepancy
+	err := models.DB.WithContext(ctx).
+		Where("contract = ? and account = ? and field = ? and status = ?", contract.Address, check.account, check.field, models.DiscrepancyOpen).
+		Order("id desc").Limit(1).Find(&open).Error
+	if err != nil {
+		return fmt.Errorf("load discrepancy: %w", err)
+	}
+	if len(open) > 0 {
+		if err := models.DB.WithContext(ctx).Model(&open[0]).Updates(values).Error; err != nil {
+			return fmt.Errorf("update discrepancy: %w", err)
+		}
+		return nil
+	}
+	discrepancy := models.ReconcileDiscrepancy{
+		Kind:         contract.Kind,
+		Contract:     contract.Address,
+		Account:      check.account,
+		Field:        check.field,
+		BlockNumber:  blockNumber,
+		IndexedValue: check.indexed.String(),
+		OnchainValue: check.onchain.String(),
+		Status:       models.DiscrepancyOpen,
+	}
+	// 同一区块已有 reindexed/resolved 记录时重新打开该记录
+	err = models.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"indexed_value", "onchain_value", "status"}),
+	}).Create(&discrepancy).Error
+	if err != nil {
+		return fmt.Errorf("record discrepancy: %w", err)
+	}
+	return nil
+}
+
+// checkStaking 对比 stakedBalance / rewards, 已索引值由截至该区块的事件重放得到;
+// 同时对比 API 读取的 staking_position.current_staked, 仓位晚于该区块更新的账户跳过这一项
+func (r *reconcilerService) checkStaking(ctx context.Context, contractAddress common.Address, block BlockRef) ([]reconcileCheck, error) {
+	var positions []models.StakingPosition
+	query := models.DB.WithContext(ctx).Where("contract = ?", contractAddress.Hex())
+	if r.sampleSize > 0 {
+		query = query.Order("RAND()").Limit(r.sampleSize)
+	}
+	if err := query.Find(&positions).Error; err != nil {
+		return nil, fmt.Errorf("load stakers: %w", err)
+	}
+	if len(positions) == 0 {
+		return nil, nil
+	}
+	height, _ := block.Height()
+	events, err := loadAccrualEvents(ctx, contractAddress.Hex(), "block_number <= ?", height)
+	if err != nil {
+		return nil, err
+	}
+	engine := accrual.NewEngine(accrual.DefaultRewardRate)
+	for _, ev := range events {
+		if err := engine.Apply(ev); err != nil {
+			return nil, fmt.Errorf("replay accrual: %w", err)
+		}
+	}
+	newStaking, err := staking.NewStaking(contractAddress, r.client)
+	if err != nil {
+		return nil, fmt.Errorf("new staking contract: %w", err)
+	}
+	checks := make([]reconcileCheck, 0, len(positions)*3)
+	for _, position := range positions {
+		user := position.User
+		account := common.HexToAddress(user)
+		indexed := engine.User(user)
+		staked, err := newStaking.StakedBalance(block.callOpts(ctx), account)
+		if err != nil {
+			return nil, fmt.Errorf("stakedBalance call: %w", block.callErr(err))
+		}
+		rewards, err := newStaking.Rewards(block.callOpts(ctx), account)
+		if err != nil {
+			return nil, fmt.Errorf("rewards call: %w", block.callErr(err))
+		}
+		checks = append(checks,
+			reconcileCheck{account: user, field: models.ReconcileFieldStakedBalance, indexed: indexed.StakedBalance, onchain: staked},
+			reconcileCheck{account: user, field: models.ReconcileFieldRewards, indexed: indexed.Rewards, onchain: rewards},
+		)
+		if position.LastBlock <= height {
+			checks = append(checks, reconcileCheck{account: user, field: models.ReconcileFieldPositionStaked, indexed: projectionAmount(position.CurrentStaked), onchain: staked})
+		}
+	}
+	return checks, nil
+}
+
+// checkErc20 对比 balanceOf, 已索引余额为截至该区块的转入减转出;
+// 同时对比 API 读取的 erc20_holder_balance, 余额晚于该区块更新的持有人跳过这一项
+func (r *reconcilerService) checkErc20(ctx context.Context, contractAddress common.Address, block BlockRef) ([]reconcileCheck, error) {
+	contract := contractAddress.Hex()
+	height, _ := block.Height()
+	var holders []string
+	query := models.DB.WithContext(ctx).Raw(`SELECT account FROM (
+  SELECT `+"`to`"+` AS account FROM erc20_event_transfer WHERE contract = ? AND block_number <= ?
+  UNION
+  SELECT `+"`from`"+` FROM erc20_event_transfer WHERE contract = ? AND block_number <= ?
+) t WHERE account <> ?`+sampleClause(r.sampleSize), contract, height, contract, height, zeroAddress)
+	if err := query.Scan(&holders).Error; err != nil {
+		return nil, fmt.Errorf("load holders: %w", err)
+	}
+	if len(holders) == 0 {
+		return nil, nil
+	}
+	balances, err := holderBalancesAt(models.DB.WithContext(ctx), contract, height, holders)
+	if err != nil {
+		return nil, err
+	}
+	var projections []models.ERC20HolderBalance
+	if err := models.DB.WithContext(ctx).Where("contract = ? and holder IN ?", contract, holders).Find(&projections).Error; err != nil {
+		return nil, fmt.Errorf("load holder balances: %w", err)
+	}
+	projected := make(map[string]models.ERC20HolderBalance, len(projections))
+	for _, row := range projections {
+		projected[row.Holder] = row
+	}
+	token, err := erc20.NewErc20(contractAddress, r.client)
+	if err != nil {
+		return nil, fmt.Errorf("new erc20 contract: %w", err)
+	}
+	checks := make([]reconcileCheck, 0, len(holders)*2)
+	for _, holder := range holders {
+		onchain, err := token.BalanceOf(block.callOpts(ctx), common.HexToAddress(holder))
+		if err != nil {
+			return nil, fmt.Errorf("balanceOf call: %w", block.callErr(err))
+		}
+		indexed, ok := balances[holder]
+		if !ok {
+			indexed = new(big.Int)
+		}
+		checks = append(checks, reconcileCheck{account: holder, field: models.ReconcileFieldBalanceOf, indexed: indexed, onchain: onchain})
+		row, ok := projected[holder]
+		if !ok {
+			checks = append(checks, reconcileCheck{account: holder, field: models.ReconcileFieldHolderBalance, indexed: new(big.Int), onchain: onchain})
+		} else if row.LastBlock <= height {
+			checks = append(checks, reconcileCheck{account: holder, field: models.ReconcileFieldHolderBalance, indexed: projectionAmount(row.Balance), onchain: onchain})
+		}
+	}
+	return checks, nil
+}
+
+// projectionAmount 投影表中的金额, 无法解析时按 0 计, 由对账记为不一致
+func projectionAmount(s string) *big.Int {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return amount
+}
+
+func sampleClause(size int) string {
+	if size <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" ORDER BY RAND() LIMIT %d", size)
+}
+
+func (r *reconcilerService) List(ctx context.Context, contractAddress *common.Address, status string, pageNum int, pageSize int) ([]models.ReconcileDiscrepancy, int64, error) {
+	query := models.DB.WithContext(ctx).Model(&models.ReconcileDiscrepancy{})
+	if contractAddress != nil {
+		query = query.Where("contract = ?", contractAddress.Hex())
+	}
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count discrepancies: %w", err)
+	}
+	var list []models.ReconcileDiscrepancy
+	err := query.Order("id desc").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list discrepancies: %w", err)
+	}
+	return list, total, nil
+}
+
+// Reindex 清理并重新索引差异所在合约 [fromBlock, 对账区块] 的事件, fromBlock 缺省为合约起始区块;
+// 下一轮对账结果一致时差异自动标记为 resolved
+func (r *reconcilerService) Reindex(ctx context.Context, id uint, fromBlock *uint64) (*models.ReconcileDiscrepancy, error) {
+	var discrepancy models.ReconcileDiscrepancy
+	if err := models.DB.WithContext(ctx).First(&discrepancy, id).Error; err != nil {
+		return nil, fmt.Errorf("get discrepancy %d: %w", id, err)
+	}
+	if discrepancy.Status == models.DiscrepancyResolved {
+		return &discrepancy, nil
+	}
+	from := uint64(0)
+	if fromBlock != nil {
+		from = *fromBlock
+	} else {
+		var contract models.WatchedContract
+		if err := models.DB.WithContext(ctx).Where("address = ?", discrepancy.Contract).First(&contract).Error; err != nil {
+			return nil, fmt.Errorf("load contract: %w", err)
+		}
+		from = contract.StartBlock
+	}
+	if from > discrepancy.BlockNumber {
+		return nil, fmt.Errorf("fromBlock %d is after discrepancy block %d", from, discrepancy.BlockNumber)
+	}
+	logger.WithModule("reconciler").WithFields(logrus.Fields{
+		"id":       discrepancy.ID,
+		"contract": discrepancy.Contract,
+		"from":     from,
+		"to":       discrepancy.BlockNumber,
+	}).Info("reindex discrepancy")
+	if err := r.registry.Reindex(ctx, common.HexToAddress(discrepancy.Contract), from, discrepancy.BlockNumber, true); err != nil {
+		return nil, err
+	}
+	// 同一合约在该区间内的其他差异也已一并重新索引
+	err := models.DB.WithContext(ctx).Model(&models.ReconcileDiscrepancy{}).
+		Where("contract = ? and status = ? and block_number <= ?", discrepancy.Contract, models.DiscrepancyOpen, discrepancy.BlockNumber).
+		Update("status", models.DiscrepancyReindexed).Error
+	if err != nil {
+		return nil, fmt.Errorf("update discrepancy: %w", err)
+	}
+	if err := models.DB.WithContext(ctx).First(&discrepancy, id).Error; err != nil {
+		return nil, fmt.Errorf("get discrepancy %d: %w", id, err)
+	}
+	return &discrepancy, nil
+}
+
+// StartLoop 定时对账全部已启用的 staking / erc20 合约
+func (r *reconcilerService) StartLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var contracts []string
+			err := models.DB.WithContext(ctx).Model(&models.WatchedContract{}).
+				Where("enabled = ? and kind IN ?", true, []string{ContractKindStaking, ContractKindERC20}).
+				Pluck("address", &contracts).Error
+			if err != nil {
+				logger.WithModule("reconciler").WithError(err).Error("load contracts failed")
+				continue
+			}
+			for _, contract := range contracts {
+				if _, err := r.Run(ctx, common.HexToAddress(contract)); err != nil {
+					logger.WithModule("reconciler").WithError(err).WithField("contract", contract).Error("reconcile failed")
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// SupervisorConfig 每个合约回放循环的运行参数
+type SupervisorConfig struct {
+	Subscribe          bool          // 订阅模式
+	Interval           time.Duration // 轮询间隔
+	CheckpointInterval time.Duration // 订阅模式下没有新区块通知时的回放间隔
+	ReloadInterval     time.Duration // 重新加载注册表的间隔, 用于感知其他实例的修改
+}
+
+// 检查点落后组内最高检查点超过该区块数的合约先单独追赶, 追上后并入该组
+const catchUpLag = 1000
+
+type RegistryService interface {
+	List(ctx context.Context) ([]models.WatchedContract, error)
+	Register(ctx context.Context, contract models.WatchedContract) (*models.WatchedContract, error)
+	SetEnabled(ctx context.Context, address common.Address, enabled bool) error
+	Remove(ctx context.Context, address common.Address) error
+	Seed(ctx context.Context, contracts []models.WatchedContract) error
+	Reindex(ctx context.Context, address common.Address, from uint64, to uint64, purge bool) error
+	Start(ctx context.Context)
+}
+
+// runningLoop 正在运行的回放循环, 同一组合约共用一次 eth_getLogs
+type runningLoop struct {
+	contracts []models.WatchedContract // 按地址排序
+	cancel    context.CancelFunc
+	done      chan struct{} // 循环退出后关闭
+}
+
+type registryService struct {
+	listener ListenerService
+	abis     AbiStore
+	cfg      SupervisorConfig
+	mu       sync.Mutex
+	ctx      context.Context
+	running  map[string]*runningLoop
+}
+
+func NewRegistryService(listener ListenerService, abis AbiStore, cfg SupervisorConfig) RegistryService {
+	return &registryService{listener: listener, abis: abis, cfg: cfg, running: make(map[string]*runningLoop)}
+}
+
+func (r *registryService) List(ctx context.Context) ([]models.WatchedContract, error) {
+	var list []models.WatchedContract
+	if err := models.DB.WithContext(ctx).Order("id").Find(&list).Error; err != nil {
+		return nil, fmt.Errorf("list watched contracts: %w", err)
+	}
+	return list, nil
+}
+
+func (r *registryService) Register(ctx context.Context, contract models.WatchedContract) (*models.WatchedContract, error) {
+	switch contract.Kind {
+	case ContractKindStaking, ContractKindERC20:
+		contract.Abi = ""
+	case ContractKindABI:
+		// 注册前确认 ABI 存在且可解析
+		if _, err := r.abis.Load(contract.Abi); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown contract kind %q", contract.Kind)
+	}
+	if !common.IsHexAddress(contract.Address) {
+		return nil, fmt.Errorf("invalid contract address %q", contract.Address)
+	}
+	contract.Address = common.HexToAddress(contract.Address).Hex()
+	contract.Enabled = true
+	err := models.DB.WithContext(ctx).Where("address = ?", contract.Address).
+		Assign(models.WatchedContract{
+			Kind:          contract.Kind,
+			Abi:           contract.Abi,
+			StartBlock:    contract.StartBlock,
+			Confirmations: contract.Confirmations,
+			Enabled:       true,
+		}).
+		FirstOrCreate(&contract).Error
+	if err != nil {
+		return nil, fmt.Errorf("register contract: %w", err)
+	}
+	r.reconcile()
+	return &contract, nil
+}
+
+func (r *registryService) SetEnabled(ctx context.Context, address common.Address, enabled bool) error {
+	result := models.DB.WithContext(ctx).Model(&models.WatchedContract{}).
+		Where("address = ?", address.Hex()).
+		Update("enabled", enabled)
+	if result.Error != nil {
+		return fmt.Errorf("update contract: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("contract %s not registered", address.Hex())
+	}
+	r.reconcile()
+	return nil
+}
+
+func (r *registryService) Remove(ctx context.Context, address common.Address) error {
+	result := models.DB.WithContext(ctx).Where("address = ?", address.Hex()).Delete(&models.WatchedContract{})
+	if result.Error != nil {
+		return fmt.Errorf("remove contract: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("contract %s not registered", address.Hex())
+	}
+	r.reconcile()
+	return nil
+}
+
+// Seed 把配置文件中的合约写入注册表, 已存在的不覆盖
+func (r *registryService) Seed(ctx context.Context, contracts []models.WatchedContract) error {
+	for _, contract := range contracts {
+		contract.Address = common.HexToAddress(contract.Address).Hex()
+		var existing models.WatchedContract
+		err := models.DB.WithContext(ctx).Where("address = ?", contract.Address).First(&existing).Error
+		if err == nil {
+			continue
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if err := models.DB.WithContext(ctx).Create(&contract).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reindex 按注册表中的合约类型重新索引指定区间, 不影响正在运行的循环与检查点
+func (r *registryService) Reindex(ctx context.Context, address common.Address, from uint64, to uint64, purge bool) error {
+	var contract models.WatchedContract
+	err := models.DB.WithContext(ctx).Where("address = ?", address.Hex()).First(&contract).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("contract %s not registered", address.Hex())
+	}
+	if err != nil {
+		return fmt.Errorf("load contract: %w", err)
+	}
+	return r.listener.ReindexRange(ctx, contractTarget(contract), from, to, contract.Confirmations, purge)
+}
+
+// Start 启动监督循环: 按注册表启停每组合约的回放循环
+func (r *registryService) Start(ctx context.Context) {
+	r.mu.Lock()
+	r.ctx = ctx
+	r.mu.Unlock()
+	r.reconcile()
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.stopAll()
+			return
+		case <-ticker.C:
+			r.reconcile()
+		}
+	}
+}
+
+// reconcile 对比注册表与正在运行的循环: 起始区块与确认数相同的合约分为一组,
+// 每组一个回放循环, 保证跨合约事件按 (区块, 日志索引) 顺序入库;
+// 落后较多的合约先单独追赶; 组内合约增删或参数变更时重启该组
+func (r *registryService) reconcile() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.ctx == nil {
+		return
+	}
+	var enabled []models.WatchedContract
+	if err := models.DB.WithContext(r.ctx).Where("enabled = ?", true).Order("address").Find(&enabled).Error; err != nil {
+		logger.WithModule("registry").WithError(err).Error("load watched contracts failed")
+		return
+	}
+	groups := make(map[string][]models.WatchedContract)
+	for _, contract := range enabled {
+		key := loopGroupKey(contract)
+		groups[key] = append(groups[key], contract)
+	}
+	checkpoints, err := loadCheckpoints(r.ctx, enabled)
+	if err != nil {
+		logger.WithModule("registry").WithError(err).Error("load checkpoints failed")
+		return
+	}
+	// 组内从 min(检查点) 回放, 新加入的合约先用单独的循环追赶到组内最高检查点附近再并入,
+	// 避免整组从新合约的起始区块重新扫描
+	desired := make(map[string][]models.WatchedContract)
+	for key, contracts := range groups {
+		members, lagging := splitLagging(contracts, checkpoints)
+		if len(members) > 0 {
+			desired[key] = members
+		}
+		for _, contract := range lagging {
+			desired[catchUpKey(contract)] = []models.WatchedContract{contract}
+		}
+	}
+	// 新循环等被停止的循环退出后再开始, 避免两个循环同时提交同一区间
+	var stopped []chan struct{}
+	for key, loop := range r.running {
+		if sameContracts(desired[key], loop.contracts) {
+			continue
+		}
+		loop.cancel()
+		stopped = append(stopped, loop.done)
+		delete(r.running, key)
+		logger.WithModule("registry").WithFields(logrus.Fields{
+			"group":     key,
+			"contracts": contractAddresses(loop.contracts),
+		}).Info("contract loop stopped")
+	}
+	for key, contracts := range desired {
+		if _, ok := r.running[key]; ok {
+			continue
+		}
+		loopCtx, cancel := context.WithCancel(r.ctx)
+		loop := &runningLoop{contracts: contracts, cancel: cancel, done: make(chan struct{})}
+		r.running[key] = loop
+		go func() {
+			defer close(loop.done)
+			for _, done := range stopped {
+				select {
+				case <-done:
+				case <-loopCtx.Done():
+					return
+				}
+			}
+			r.run(loopCtx, contracts)
+		}()
+		logger.WithModule("registry").WithFields(logrus.Fields{
+			"group":     key,
+			"contracts": contractAddresses(contracts),
+		}).Info("contract loop started")
+	}
+}
+
+// run 组内合约的起始区块与确认数相同
+func (r *registryService) run(ctx context.Context, contracts []models.WatchedContract) {
+	targets := make([]WatchTarget, 0, len(contracts))
+	for _, contract := range contracts {
+		targets = append(targets, contractTarget(contract))
+	}
+	startBlock, confirmations := contracts[0].StartBlock, contracts[0].Confirmations
+	if r.cfg.Subscribe {
+		// 订阅模式: 新区块触发回放, 内部先按 sync_state 补齐
+		r.listener.StartSubscribeLoop(ctx, targets, startBlock, confirmations, r.cfg.CheckpointInterval)
+		return
+	}
+	// 调用区块链回放
+	if err := r.listener.ReplayFromLast(ctx, targets, startBlock, confirmations); err != nil {
+		logger.WithModule("listener").WithError(err).WithField("contracts", contractAddresses(contracts)).Error("replay from last failed")
+	}
+	r.listener.StartReplayLoop(ctx, targets, startBlock, confirmations, r.cfg.Interval)
+}
+
+func (r *registryService) stopAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for key, loop := range r.running {
+		loop.cancel()
+		delete(r.running, key)
+	}
+}
+
+func contractTarget(contract models.WatchedContract) WatchTarget {
+	return WatchTarget{Kind: contract.Kind, Address: common.HexToAddress(contract.Address), AbiName: contract.Abi}
+}
+
+// catchUpKey 追赶中的合约单独一个循环
+func catchUpKey(contract models.WatchedContract) string {
+	return "catchup:" + contract.Address
+}
+
+// loadCheckpoints 合约地址到已同步区块, 没有检查点时为起始区块的前一个区块
+func loadCheckpoints(ctx context.Context, contracts []models.WatchedContract) (map[string]uint64, error) {
+	if len(contracts) == 0 {
+		return nil, nil
+	}
+	keys := make([]string, 0, len(contracts))
+	for _, contract := range contracts {
+		keys = append(keys, contractTarget(contract).syncKey())
+	}
+	var states []models.SyncState
+	if err := models.DB.WithContext(ctx).Where("name IN ?", keys).Find(&states).Error; err != nil {
+		return nil, err
+	}
+	synced := make(map[string]uint64, len(states))
+	for _, state := range states {
+		synced[state.Name] = state.BlockNumber
+	}
+	checkpoints := make(map[string]uint64, len(contracts))
+	for _, contract := range contracts {
+		checkpoint, ok := synced[contractTarget(contract).syncKey()]
+		if !ok && contract.StartBlock > 0 {
+			checkpoint = contract.StartBlock - 1
+		}
+		checkpoints[contract.Address] = checkpoint
+	}
+	return checkpoints, nil
+}
+
+// splitLagging 检查点落后组内最高检查点超过 catchUpLag 的合约需要先单独追赶
+func splitLagging(contracts []models.WatchedContract, checkpoints map[string]uint64) ([]models.WatchedContract, []models.WatchedContract) {
+	var head uint64
+	for _, contract := range contracts {
+		if checkpoints[contract.Address] > head {
+			head = checkpoints[contract.Address]
+		}
+	}
+	members := make([]models.WatchedContract, 0, len(contracts))
+	var lagging []models.WatchedContract
+	for _, contract := range contracts {
+		if checkpoints[contract.Address]+catchUpLag < head {
+			lagging = append(lagging, contract)
+			continue
+		}
+		members = append(members, contract)
+	}
+	return members, lagging
+}
+
+func loopGroupKey(contract models.WatchedContract) string {
+	return fmt.Sprintf("start=%d,confirmations=%d", contract.StartBlock, contract.Confirmations)
+}
+
+// sameContracts 两组合约 (均按地址排序) 的地址、类型与 ABI 是否一致
+func sameContracts(a []models.WatchedContract, b []models.WatchedContract) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Address != b[i].Address || a[i].Kind != b[i].Kind || a[i].Abi != b[i].Abi {
+			return false
+		}
+	}
+	return true
+}
+
+func contractAddresses(contracts []models.WatchedContract) []string {
+	addresses := make([]string, 0, len(contracts))
+	for _, contract := range contracts {
+		addresses = append(addresses, contract.Address)
+	}
+	return addresses
+}
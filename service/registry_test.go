@@ -0,0 +1,34 @@
+package service
+
+import (
+	"go-solidity-staking/models"
+	"testing"
+)
+
+func TestSplitLagging(t *testing.T) {
+	contracts := []models.WatchedContract{
+		{Address: "0xA"},
+		{Address: "0xB"},
+		{Address: "0xC"},
+		{Address: "0xD"},
+	}
+	checkpoints := map[string]uint64{
+		"0xA": 50000,
+		"0xB": 49990,              // 刚并入的合约, 落后不多
+		"0xC": 99,                 // 新注册, 从起始区块开始
+		"0xD": 50000 - catchUpLag, // 恰好在容差内
+	}
+	members, lagging := splitLagging(contracts, checkpoints)
+	if len(members) != 3 || members[0].Address != "0xA" || members[1].Address != "0xB" || members[2].Address != "0xD" {
+		t.Fatalf("members = %v, want 0xA 0xB 0xD", contractAddresses(members))
+	}
+	if len(lagging) != 1 || lagging[0].Address != "0xC" {
+		t.Fatalf("lagging = %v, want 0xC", contractAddresses(lagging))
+	}
+
+	// 整组都没有检查点时一起从起始区块回放
+	members, lagging = splitLagging(contracts, map[string]uint64{})
+	if len(members) != len(contracts) || len(lagging) != 0 {
+		t.Fatalf("members = %v, lagging = %v, want all members", contractAddresses(members), contractAddresses(lagging))
+	}
+}
@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// ReindexRange 重新索引合约在 [from, to] 内的事件, 不读写 sync_state, 可与实时循环并行;
+// to 不能超过实时检查点与最新已确认区块, 否则写入的未确认区块不会被实时循环的重组检测回滚;
+// 日志所在区块与实时循环记录的 sync_block 哈希不一致时放弃该分段.
+// purge 为 true 时每个分段先删除区间内已有的事件行, 删除与写入在同一事务提交
+func (l *listenerService) ReindexRange(ctx context.Context, target WatchTarget, from uint64, to uint64, confirmations uint64, purge bool) error {
+	if from > to {
+		return fmt.Errorf("invalid block range %d-%d", from, to)
+	}
+	limit, err := l.reindexLimit(ctx, target, confirmations)
+	if err != nil {
+		return err
+	}
+	if to > limit {
+		return fmt.Errorf("reindex range end %d is beyond indexed confirmed block %d", to, limit)
+	}
+	logger.WithModule("listener").WithFields(logrus.Fields{
+		"contract": target.Address.Hex(),
+		"kind":     target.Kind,
+		"from":     from,
+		"to":       to,
+		"purge":    purge,
+	}).Info("reindex range started")
+	// 分段大小与实时回放分开调节
+	key := "reindex:" + target.syncKey()
+	err = l.replayChunked(ctx, key, from, to, func(start uint64, end uint64) error {
+		return l.indexRange(ctx, []WatchTarget{target}, start, end, rangeOptions{purge: purge, verifyHashes: true})
+	})
+	if err != nil {
+		return fmt.Errorf("reindex %s: %w", target.Address.Hex(), err)
+	}
+	logger.WithModule("listener").WithField("contract", target.Address.Hex()).Info("reindex range finished")
+	return nil
+}
+
+// reindexLimit min(实时检查点, 最新已确认区块); 没有检查点时只受确认数限制
+func (l *listenerService) reindexLimit(ctx context.Context, target WatchTarget, confirmations uint64) (uint64, error) {
+	latestHeader, err := l.client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	limit := latestHeader.Number.Uint64()
+	if confirmations > 1 {
+		if limit < confirmations-1 {
+			return 0, fmt.Errorf("no confirmed block at head %d", limit)
+		}
+		limit -= confirmations - 1
+	}
+	state, err := l.getSyncState(target.syncKey())
+	if err != nil {
+		return 0, err
+	}
+	if state != nil && state.BlockNumber < limit {
+		limit = state.BlockNumber
+	}
+	return limit, nil
+}
+
+// checkRecordedHashes 日志所在区块与实时循环记录的区块哈希不一致, 说明链已重组而实时循环尚未回滚
+func checkRecordedHashes(tx *gorm.DB, key string, logs []decodedLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	numbers := make([]uint64, 0, len(logs))
+	seen := make(map[uint64]bool, len(logs))
+	for _, decoded := range logs {
+		if !seen[decoded.log.BlockNumber] {
+			seen[decoded.log.BlockNumber] = true
+			numbers = append(numbers, decoded.log.BlockNumber)
+		}
+	}
+	var blocks []models.SyncBlock
+	if err := tx.Where("name = ? and block_number IN ?", key, numbers).Find(&blocks).Error; err != nil {
+		return err
+	}
+	recorded := make(map[uint64]string, len(blocks))
+	for _, block := range blocks {
+		recorded[block.BlockNumber] = block.BlockHash
+	}
+	for _, decoded := range logs {
+		hash, ok := recorded[decoded.log.BlockNumber]
+		if ok && hash != decoded.log.BlockHash.Hex() {
+			return fmt.Errorf("log block %d hash %s differs from indexed %s, chain reorganized", decoded.log.BlockNumber, decoded.log.BlockHash.Hex(), hash)
+		}
+	}
+	return nil
+}
+
+// purgeRange 删除合约在 [start, end] 内的明细行与 event_log 行, 并按剩余明细重算投影
+func purgeRange(tx *gorm.DB, target WatchTarget, start uint64, end uint64) error {
+	contract := target.Address.Hex()
+	affected, err := collectAffected(tx, contract, "block_number between ? and ?", start, end)
+	if err != nil {
+		return err
+	}
+	for _, model := range target.eventModels() {
+		if err := tx.Where("contract = ? and block_number between ? and ?", contract, start, end).Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	if err := affected.refresh(tx); err != nil {
+		return err
+	}
+	return tx.Where("contract = ? and block_number between ? and ?", contract, start, end).Delete(&models.EventLog{}).Error
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 保留的区块哈希数量, 超过该深度的重组无法自动回滚
+const reorgWindow = 256
+
+var (
+	errReorgTooDeep  = errors.New("reorg deeper than tracked block window")
+	errRangeUnlinked = errors.New("range does not link to checkpoint, chain reorganized")
+)
+
+var (
+	stakingEventModels = []interface{}{
+		&models.StakingEventStaked{},
+		&models.StakingEventWithdrawn{},
+		&models.StakingEventRewardsClaimed{},
+		&models.StakingEventRewardRateUpdated{},
+		&models.StakingEventOwnershipTransferred{},
+	}
+	erc20EventModels = []interface{}{
+		&models.ERC20EventTransfer{},
+		&models.ERC20EventApproval{},
+	}
+)
+
+// detectReorg 比较检查点下一个区块的 parentHash 与已记录的哈希,
+// 不一致时回滚到分叉点并返回新的检查点高度
+func (l *listenerService) detectReorg(ctx context.Context, key string, contractAddress common.Address, eventModels []interface{}, lastBlock uint64) (uint64, error) {
+	state, err := l.getSyncState(key)
+	if err != nil {
+		return lastBlock, err
+	}
+	if state == nil || state.BlockHash == "" {
+		return lastBlock, nil
+	}
+	next, err := l.client.HeaderByNumber(ctx, new(big.Int).SetUint64(state.BlockNumber+1))
+	if err != nil {
+		return lastBlock, fmt.Errorf("get header %d: %w", state.BlockNumber+1, err)
+	}
+	if next.ParentHash.Hex() == state.BlockHash {
+		return lastBlock, nil
+	}
+	fork, err := l.findForkPoint(ctx, key, state.BlockNumber)
+	if err != nil {
+		return lastBlock, err
+	}
+	logger.WithModule("listener").WithFields(logrus.Fields{
+		"contract":   contractAddress.Hex(),
+		"checkpoint": state.BlockNumber,
+		"fork":       fork.BlockNumber,
+	}).Warn("chain reorg detected, rolling back")
+	if err := l.rollbackTo(key, contractAddress, eventModels, fork); err != nil {
+		return lastBlock, fmt.Errorf("rollback to %d: %w", fork.BlockNumber, err)
+	}
+	return fork.BlockNumber, nil
+}
+
+// findForkPoint 从检查点向下查找仍在规范链上的最高已记录区块;
+// 窗口内每个区块都记录了哈希, 找到的即为分叉点
+func (l *listenerService) findForkPoint(ctx context.Context, key string, checkpoint uint64) (*models.SyncBlock, error) {
+	var blocks []models.SyncBlock
+	err := models.DB.Where("name = ? and block_number <= ?", key, checkpoint).
+		Order("block_number desc").
+		Limit(reorgWindow).
+		Find(&blocks).Error
+	if err != nil {
+		return nil, err
+	}
+	for i := range blocks {
+		header, err := l.client.HeaderByNumber(ctx, new(big.Int).SetUint64(blocks[i].BlockNumber))
+		if err != nil {
+			return nil, fmt.Errorf("get header %d: %w", blocks[i].BlockNumber, err)
+		}
+		if header.Hash().Hex() == blocks[i].BlockHash {
+			return &blocks[i], nil
+		}
+	}
+	return nil, errReorgTooDeep
+}
+
+// rollbackTo 删除分叉点之上的事件、死信与区块记录, 并把检查点退回分叉点
+func (l *listenerService) rollbackTo(key string, contractAddress common.Address, eventModels []interface{}, fork *models.SyncBlock) error {
+	contract := contractAddress.Hex()
+	return models.DB.Transaction(func(tx *gorm.DB) error {
+		// 被回滚事件涉及的投影, 删除后按剩余明细重算
+		affected, err := collectAffected(tx, contract, "block_number > ?", fork.BlockNumber)
+		if err != nil {
+			return err
+		}
+		for _, model := range eventModels {
+			if err := tx.Where("contract = ? and block_number > ?", contract, fork.BlockNumber).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+		if err := affected.refresh(tx); err != nil {
+			return err
+		}
+		if err := tx.Where("contract = ? and block_number > ?", contract, fork.BlockNumber).Delete(&models.EventLog{}).Error; err != nil {
+			return err
+		}
+		// 孤块上的死信重试会写入已不在规范链上的日志
+		if err := tx.Where("contract = ? and block_number > ?", contract, fork.BlockNumber).Delete(&models.DeadLetterEvent{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("name = ? and block_number > ?", key, fork.BlockNumber).Delete(&models.SyncBlock{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.SyncState{}).Where("name = ?", key).Updates(map[string]interface{}{
+			"block_number": fork.BlockNumber,
+			"block_hash":   fork.BlockHash,
+		}).Error
+	})
+}
+
+// blockHash 已处理区块的哈希
+type blockHash struct {
+	number uint64
+	hash   common.Hash
+}
+
+// hashWindowStart 需要记录哈希的最低区块, 与清理旧记录的窗口一致
+func hashWindowStart(latest uint64) uint64 {
+	if latest < reorgWindow {
+		return 0
+	}
+	return latest - reorgWindow + 1
+}
+
+// fetchBlockHashes 拉取 [max(start, from), end] 每个区块的区块头, 区间末块总会包含在内,
+// 并返回区间首块的 parentHash, 提交时用于确认与上一区间相连;
+// 相邻区块的 parentHash 不连续说明拉取期间发生了重组, 返回错误由下一轮重试
+func (l *listenerService) fetchBlockHashes(ctx context.Context, start uint64, end uint64, from uint64) ([]blockHash, common.Hash, error) {
+	if from < start {
+		from = start
+	}
+	if from > end {
+		from = end
+	}
+	numbers := make([]uint64, 0, end-from+2)
+	// 首块不在记录范围内时单独取一次区块头
+	if from > start {
+		numbers = append(numbers, start)
+	}
+	for number := from; number <= end; number++ {
+		numbers = append(numbers, number)
+	}
+	headers := make([]*types.Header, len(numbers))
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(headerFetchWorkers)
+	for i, number := range numbers {
+		g.Go(func() error {
+			header, err := l.client.HeaderByNumber(gctx, new(big.Int).SetUint64(number))
+			if err != nil {
+				return fmt.Errorf("get header %d: %w", number, err)
+			}
+			headers[i] = header
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, common.Hash{}, err
+	}
+	parent := headers[0].ParentHash
+	if from > start {
+		headers = headers[1:]
+	}
+	blocks := make([]blockHash, len(headers))
+	for i, header := range headers {
+		if i > 0 && header.ParentHash != blocks[i-1].hash {
+			return nil, common.Hash{}, fmt.Errorf("block %s parent hash changed while fetching headers, chain reorganized", header.Number)
+		}
+		blocks[i] = blockHash{number: header.Number.Uint64(), hash: header.Hash()}
+		// 同一批区块的日志补齐时间时直接命中
+		l.headers.put(blocks[i].number, blocks[i].hash, header.Time)
+	}
+	return blocks, parent, nil
+}
+
+// checkRangeLink 区间首块的 parentHash 必须等于已提交的检查点哈希 (上一区间的末块),
+// 并发拉取的区间之间发生重组时在提交前发现
+func checkRangeLink(tx *gorm.DB, batch *rangeBatch) error {
+	if batch.start == 0 {
+		return nil
+	}
+	for _, target := range batch.targets {
+		var states []models.SyncState
+		if err := tx.Where("name = ?", target.syncKey()).Limit(1).Find(&states).Error; err != nil {
+			return err
+		}
+		if len(states) == 0 || states[0].BlockHash == "" || states[0].BlockNumber != batch.start-1 {
+			continue
+		}
+		if states[0].BlockHash != batch.parent.Hex() {
+			return fmt.Errorf("%w: block %d parent %s, checkpoint %s", errRangeUnlinked, batch.start, batch.parent.Hex(), states[0].BlockHash)
+		}
+	}
+	return nil
+}
+
+// rollbackUnlinked 区间与检查点不相连时按检查点检测重组并回滚, 下一轮从分叉点继续
+func (l *listenerService) rollbackUnlinked(ctx context.Context, targets []WatchTarget) error {
+	for _, target := range targets {
+		lastBlock, err := l.getSyncBlock(target.syncKey())
+		if err != nil {
+			return err
+		}
+		if _, err := l.detectReorg(ctx, target.syncKey(), target.Address, target.eventModels(), lastBlock); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkLogHashes 日志所在区块与已取的区块头不一致时, 说明两次请求之间发生了重组
+func checkLogHashes(logs []types.Log, blocks []blockHash) error {
+	if len(blocks) == 0 {
+		return nil
+	}
+	hashes := make(map[uint64]common.Hash, len(blocks))
+	for _, block := range blocks {
+		hashes[block.number] = block.hash
+	}
+	for _, logEntry := range logs {
+		if hash, ok := hashes[logEntry.BlockNumber]; ok && hash != logEntry.BlockHash {
+			return fmt.Errorf("log block %d hash %s differs from header %s, chain reorganized", logEntry.BlockNumber, logEntry.BlockHash.Hex(), hash.Hex())
+		}
+	}
+	return nil
+}
+
+// recordSyncBlocks 记录已处理区块的哈希, 并清理超出窗口的旧记录
+func (l *listenerService) recordSyncBlocks(tx *gorm.DB, key string, blocks []blockHash) error {
+	entries := make([]models.SyncBlock, 0, len(blocks))
+	for _, block := range blocks {
+		entries = append(entries, models.SyncBlock{Name: key, BlockNumber: block.number, BlockHash: block.hash.Hex()})
+	}
+	err := tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.AssignmentColumns([]string{"block_hash"}),
+	}).Create(&entries).Error
+	if err != nil {
+		return err
+	}
+	last := blocks[len(blocks)-1].number
+	if last <= reorgWindow {
+		return nil
+	}
+	return tx.Where("name = ? and block_number < ?", key, last-reorgWindow).Delete(&models.SyncBlock{}).Error
+}
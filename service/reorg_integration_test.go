@@ -0,0 +1,365 @@
+//go:build integration
+
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-solidity-staking/gen/erc20"
+	"go-solidity-staking/internal/testchain"
+	"go-solidity-staking/models"
+	"math/big"
+	"os"
+	"testing"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient/simulated"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// 需要 MySQL 并已执行 scripts/create_event_detail_tables.sql:
+// APP_CONFIG_PATH=../config/staking.ini go test -tags integration ./service
+
+func TestMain(m *testing.M) {
+	if os.Getenv("APP_CONFIG_PATH") == "" {
+		os.Setenv("APP_CONFIG_PATH", "../config/staking.ini")
+	}
+	models.Init()
+	models.DB.Logger = gormlogger.Discard
+	os.Exit(m.Run())
+}
+
+// TestReorgRollback 索引 N 个区块后在 N-k 分叉出更长的链,
+// 分叉点之上的事件应被删除并按新链重新索引, 余额投影与链上一致
+func TestReorgRollback(t *testing.T) {
+	const (
+		blocks = 20
+		depth  = 5
+	)
+	ctx := context.Background()
+	other := crypto.PubkeyToAddress(testchain.OtherKey.PublicKey)
+	ether := testchain.Ether
+	sim := testchain.NewBackend(t, testchain.OwnerKey, testchain.OtherKey)
+	client := sim.Client()
+	ownerAuth := testchain.Transactor(t, ctx, client, testchain.OwnerKey)
+	otherAuth := testchain.Transactor(t, ctx, client, testchain.OtherKey)
+	address, token, target := deployIndexedToken(t, sim, ownerAuth)
+
+	if _, err := token.Transfer(ownerAuth, other, ether); err != nil {
+		t.Fatalf("transfer: %v", err)
+	}
+	sim.Commit()
+	var lastTransfer *types.Transaction
+	for i := 0; ; i++ {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			t.Fatalf("block number: %v", err)
+		}
+		if head >= blocks {
+			break
+		}
+		tx, err := token.Transfer(ownerAuth, common.BigToAddress(big.NewInt(int64(0x1000+i))), big.NewInt(int64(i+1)))
+		if err != nil {
+			t.Fatalf("transfer: %v", err)
+		}
+		lastTransfer = tx
+		sim.Commit()
+	}
+
+	// 最后一笔转账所在区块在分叉后成为孤块
+	orphan, err := client.TransactionReceipt(ctx, lastTransfer.Hash())
+	if err != nil {
+		t.Fatalf("orphan receipt: %v", err)
+	}
+	orphanLog := *orphan.Logs[0]
+	orphanLog.BlockTimestamp = 0
+
+	listener := NewListenerService(client, ChunkConfig{Initial: 4, Min: 1, Max: 4, Concurrency: 1}, nil).(*listenerService)
+	if err := listener.ReplayFromLast(ctx, []WatchTarget{target}, 1, 1); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	assertIndexedMatchesChain(t, ctx, client, token, target)
+	var recorded int64
+	if err := models.DB.Model(&models.SyncBlock{}).Where("name = ?", target.syncKey()).Count(&recorded).Error; err != nil {
+		t.Fatalf("count sync blocks: %v", err)
+	}
+	if recorded != blocks {
+		t.Fatalf("recorded %d block hashes, want every block 1..%d", recorded, blocks)
+	}
+
+	// 在 N-k 分叉, 新链由另一个账户转账, 比原链多一个区块
+	forkHeader, err := client.HeaderByNumber(ctx, big.NewInt(blocks-depth))
+	if err != nil {
+		t.Fatalf("fork header: %v", err)
+	}
+	if err := sim.Fork(forkHeader.Hash()); err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	sim.Rollback()
+	for i := 0; i <= depth; i++ {
+		if _, err := token.Transfer(otherAuth, common.BigToAddress(big.NewInt(int64(0x2000+i))), big.NewInt(int64(i+1))); err != nil {
+			t.Fatalf("fork transfer: %v", err)
+		}
+		sim.Commit()
+	}
+
+	// 孤块上的死信, 回滚时应被删除, 重试时区块头校验也应拒绝
+	if err := listener.headers.fillBlockTimestamp(ctx, &orphanLog); err == nil {
+		t.Fatalf("orphan log at block %d passed the header hash check", orphanLog.BlockNumber)
+	}
+	if err := models.DB.Transaction(func(tx *gorm.DB) error {
+		return recordDeadLetter(tx, target.Kind, orphanLog, errors.New("test"))
+	}); err != nil {
+		t.Fatalf("record dead letter: %v", err)
+	}
+
+	fork, err := listener.detectReorg(ctx, target.syncKey(), address, target.eventModels(), blocks)
+	if err != nil {
+		t.Fatalf("detect reorg: %v", err)
+	}
+	if fork != blocks-depth {
+		t.Fatalf("rolled back to %d, want fork point %d", fork, blocks-depth)
+	}
+	var above int64
+	if err := models.DB.Model(&models.ERC20EventTransfer{}).Where("contract = ? and block_number > ?", address.Hex(), fork).Count(&above).Error; err != nil {
+		t.Fatalf("count transfers: %v", err)
+	}
+	if above != 0 {
+		t.Fatalf("%d transfers above fork point left after rollback", above)
+	}
+	if err := models.DB.Model(&models.DeadLetterEvent{}).Where("contract = ? and block_number > ?", address.Hex(), fork).Count(&above).Error; err != nil {
+		t.Fatalf("count dead letters: %v", err)
+	}
+	if above != 0 {
+		t.Fatalf("%d dead letters above fork point left after rollback", above)
+	}
+
+	if err := listener.ReplayFromLast(ctx, []WatchTarget{target}, 1, 1); err != nil {
+		t.Fatalf("replay after reorg: %v", err)
+	}
+	assertIndexedMatchesChain(t, ctx, client, token, target)
+}
+
+// deployIndexedToken 部署测试代币, 并在测试前后清理其索引数据
+func deployIndexedToken(t *testing.T, sim *simulated.Backend, auth *bind.TransactOpts) (common.Address, *erc20.Erc20, WatchTarget) {
+	t.Helper()
+	address, _, token, err := erc20.DeployErc20(auth, sim.Client(), "Test", "TST", new(big.Int).Mul(big.NewInt(1000000), testchain.Ether))
+	if err != nil {
+		t.Fatalf("deploy erc20: %v", err)
+	}
+	sim.Commit()
+	target := WatchTarget{Kind: ContractKindERC20, Address: address}
+	cleanupIndexed(t, target)
+	t.Cleanup(func() { cleanupIndexed(t, target) })
+	return address, token, target
+}
+
+// assertIndexedMatchesChain 明细行与规范链上的 Transfer 日志一一对应, 检查点为链头, 持有人余额等于 balanceOf
+func assertIndexedMatchesChain(t *testing.T, ctx context.Context, client simulated.Client, token *erc20.Erc20, target WatchTarget) {
+	t.Helper()
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatalf("head: %v", err)
+	}
+	logs, err := client.FilterLogs(ctx, ethereum.FilterQuery{
+		FromBlock: big.NewInt(1),
+		ToBlock:   head.Number,
+		Addresses: []common.Address{target.Address},
+		Topics:    [][]common.Hash{{erc20ABI.Events["Transfer"].ID}},
+	})
+	if err != nil {
+		t.Fatalf("filter logs: %v", err)
+	}
+	want := make(map[string]uint64, len(logs))
+	for _, logEntry := range logs {
+		want[fmt.Sprintf("%s-%d", logEntry.TxHash.Hex(), logEntry.Index)] = logEntry.BlockNumber
+	}
+	var rows []models.ERC20EventTransfer
+	if err := models.DB.Where("contract = ?", target.Address.Hex()).Find(&rows).Error; err != nil {
+		t.Fatalf("load transfers: %v", err)
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("indexed %d transfers, chain has %d", len(rows), len(want))
+	}
+	for _, row := range rows {
+		block, ok := want[fmt.Sprintf("%s-%d", row.TxHash, row.LogIndex)]
+		if !ok || block != row.BlockNumber {
+			t.Fatalf("transfer %s-%d at block %d not on canonical chain", row.TxHash, row.LogIndex, row.BlockNumber)
+		}
+	}
+
+	var state models.SyncState
+	if err := models.DB.Where("name = ?", target.syncKey()).First(&state).Error; err != nil {
+		t.Fatalf("load sync state: %v", err)
+	}
+	if state.BlockNumber != head.Number.Uint64() || state.BlockHash != head.Hash().Hex() {
+		t.Fatalf("checkpoint %d %s, want head %d %s", state.BlockNumber, state.BlockHash, head.Number, head.Hash().Hex())
+	}
+
+	var balances []models.ERC20HolderBalance
+	if err := models.DB.Where("contract = ?", target.Address.Hex()).Find(&balances).Error; err != nil {
+		t.Fatalf("load holder balances: %v", err)
+	}
+	for _, balance := range balances {
+		onchain, err := token.BalanceOf(&bind.CallOpts{Context: ctx}, common.HexToAddress(balance.Holder))
+		if err != nil {
+			t.Fatalf("balanceOf: %v", err)
+		}
+		if onchain.String() != balance.Balance {
+			t.Fatalf("holder %s indexed balance %s, on-chain %s", balance.Holder, balance.Balance, onchain)
+		}
+	}
+}
+
+func cleanupIndexed(t *testing.T, target WatchTarget) {
+	contract := target.Address.Hex()
+	for _, model := range []interface{}{
+		&models.EventLog{},
+		&models.ERC20EventTransfer{},
+		&models.ERC20EventApproval{},
+		&models.ERC20HolderBalance{},
+		&models.ERC20Allowance{},
+		&models.DeadLetterEvent{},
+	} {
+		if err := models.DB.Where("contract = ?", contract).Delete(model).Error; err != nil {
+			t.Fatalf("cleanup: %v", err)
+		}
+	}
+	for _, model := range []interface{}{&models.SyncState{}, &models.SyncBlock{}} {
+		if err := models.DB.Where("name = ?", target.syncKey()).Delete(model).Error; err != nil {
+			t.Fatalf("cleanup: %v", err)
+		}
+	}
+}
+
+// TestParallelRangeLink 两个区间的拉取之间发生重组: 后一个区间首块的 parentHash
+// 与已提交的检查点不一致时拒绝提交, 并回滚到分叉点
+func TestParallelRangeLink(t *testing.T) {
+	const fork = 5
+	ctx := context.Background()
+	sim := testchain.NewBackend(t, testchain.OwnerKey)
+	client := sim.Client()
+	ownerAuth := testchain.Transactor(t, ctx, client, testchain.OwnerKey)
+	_, token, target := deployIndexedToken(t, sim, ownerAuth)
+	transfer := func(i int) {
+		if _, err := token.Transfer(ownerAuth, common.BigToAddress(big.NewInt(int64(0x3000+i))), big.NewInt(int64(i+1))); err != nil {
+			t.Fatalf("transfer: %v", err)
+		}
+		sim.Commit()
+	}
+	for i := 0; i < 9; i++ {
+		transfer(i)
+	}
+
+	listener := NewListenerService(client, ChunkConfig{Initial: 10, Min: 1, Max: 10, Concurrency: 2}, nil).(*listenerService)
+	opts := rangeOptions{checkpoint: true}
+	first, err := listener.fetchRange(ctx, []WatchTarget{target}, 1, 10, opts)
+	if err != nil {
+		t.Fatalf("fetch first range: %v", err)
+	}
+	if err := listener.commitRange(first, opts); err != nil {
+		t.Fatalf("commit first range: %v", err)
+	}
+
+	// 第一个区间提交后链在 fork 处重组, 第二个区间从新链拉取
+	forkHeader, err := client.HeaderByNumber(ctx, big.NewInt(fork))
+	if err != nil {
+		t.Fatalf("fork header: %v", err)
+	}
+	if err := sim.Fork(forkHeader.Hash()); err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	sim.Rollback()
+	for i := 0; i < 10; i++ {
+		transfer(0x100 + i)
+	}
+	second, err := listener.fetchRange(ctx, []WatchTarget{target}, 11, 15, opts)
+	if err != nil {
+		t.Fatalf("fetch second range: %v", err)
+	}
+	if err := listener.commitRange(second, opts); !errors.Is(err, errRangeUnlinked) {
+		t.Fatalf("commit second range: %v, want %v", err, errRangeUnlinked)
+	}
+	if err := listener.rollbackUnlinked(ctx, []WatchTarget{target}); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	checkpoint, err := listener.getSyncBlock(target.syncKey())
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+	if checkpoint != fork {
+		t.Fatalf("checkpoint %d after rollback, want fork point %d", checkpoint, fork)
+	}
+
+	if err := listener.ReplayFromLast(ctx, []WatchTarget{target}, 1, 1); err != nil {
+		t.Fatalf("replay after reorg: %v", err)
+	}
+	assertIndexedMatchesChain(t, ctx, client, token, target)
+}
+
+// TestReindexRangeBounds 重新索引不能越过检查点与已确认区块, 链已重组而实时循环尚未回滚时拒绝写入
+func TestReindexRangeBounds(t *testing.T) {
+	const fork = 6
+	ctx := context.Background()
+	sim := testchain.NewBackend(t, testchain.OwnerKey)
+	client := sim.Client()
+	ownerAuth := testchain.Transactor(t, ctx, client, testchain.OwnerKey)
+	_, token, target := deployIndexedToken(t, sim, ownerAuth)
+	transfer := func(i int) {
+		if _, err := token.Transfer(ownerAuth, common.BigToAddress(big.NewInt(int64(0x4000+i))), big.NewInt(int64(i+1))); err != nil {
+			t.Fatalf("transfer: %v", err)
+		}
+		sim.Commit()
+	}
+	for i := 0; i < 9; i++ {
+		transfer(i)
+	}
+	listener := NewListenerService(client, ChunkConfig{Initial: 4, Min: 1, Max: 4, Concurrency: 1}, nil).(*listenerService)
+	if err := listener.ReplayFromLast(ctx, []WatchTarget{target}, 1, 1); err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	checkpoint, err := listener.getSyncBlock(target.syncKey())
+	if err != nil {
+		t.Fatalf("load checkpoint: %v", err)
+	}
+
+	// 实时循环尚未处理的新区块
+	transfer(100)
+	if err := listener.ReindexRange(ctx, target, 1, checkpoint+1, 1, true); err == nil {
+		t.Fatalf("reindex beyond checkpoint %d succeeded", checkpoint)
+	}
+	// 检查点之内但未达到确认数
+	if err := listener.ReindexRange(ctx, target, 1, checkpoint, 3, true); err == nil {
+		t.Fatalf("reindex of unconfirmed block %d succeeded", checkpoint)
+	}
+	if err := listener.ReindexRange(ctx, target, 1, checkpoint, 1, true); err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+
+	// 重组后实时循环回滚前, 重新索引不能写入新链的日志
+	forkHeader, err := client.HeaderByNumber(ctx, big.NewInt(fork))
+	if err != nil {
+		t.Fatalf("fork header: %v", err)
+	}
+	if err := sim.Fork(forkHeader.Hash()); err != nil {
+		t.Fatalf("fork: %v", err)
+	}
+	sim.Rollback()
+	for i := 0; i < 8; i++ {
+		transfer(0x200 + i)
+	}
+	if err := listener.ReindexRange(ctx, target, fork+1, checkpoint, 1, true); err == nil {
+		t.Fatalf("reindex across unhandled reorg succeeded")
+	}
+
+	if err := listener.ReplayFromLast(ctx, []WatchTarget{target}, 1, 1); err != nil {
+		t.Fatalf("replay after reorg: %v", err)
+	}
+	assertIndexedMatchesChain(t, ctx, client, token, target)
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-solidity-staking/accrual"
+	"go-solidity-staking/models"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"gorm.io/gorm"
+)
+
+type RewardAccrualService interface {
+	EarnedAt(ctx context.Context, contractAddress common.Address, account common.Address, timestamp uint64) (*RewardAccrual, error)
+	RateHistory(ctx context.Context, contractAddress common.Address, timestamp uint64) (*RewardRateHistory, error)
+}
+
+// RewardAccrual 按已索引事件重放得到的合约状态, 金额为最小单位
+type RewardAccrual struct {
+	Contract               string `json:"contract"`
+	Account                string `json:"account"`
+	Timestamp              uint64 `json:"timestamp"`
+	Earned                 string `json:"earned"`
+	StakedBalance          string `json:"stakedBalance"`
+	Rewards                string `json:"rewards"`
+	UserRewardPerTokenPaid string `json:"userRewardPerTokenPaid"`
+	RewardPerToken         string `json:"rewardPerToken"`
+	RewardPerTokenStored   string `json:"rewardPerTokenStored"`
+	RewardRate             string `json:"rewardRate"`
+	TotalStaked            string `json:"totalStaked"`
+	LastUpdateTime         uint64 `json:"lastUpdateTime"`
+	EventCount             int    `json:"eventCount"`
+	LastEventBlock         uint64 `json:"lastEventBlock"`
+}
+
+// RewardRatePeriod 一段 rewardRate 生效期, emitted 为按该速率与质押时长计算的奖励总量
+// (用户 earned 按整数除法向下取整, 实际可领取的合计可能略少)
+type RewardRatePeriod struct {
+	RewardRate string `json:"rewardRate"`
+	// Initial 为部署时的默认速率, 起点取首个已索引事件
+	Initial    bool   `json:"initial"`
+	StartBlock uint64 `json:"startBlock"`
+	StartTime  uint64 `json:"startTime"`
+	// EffectiveFrom updateRewardRate 不结算, 新速率从上次 updateReward 起计息, 不晚于 startTime
+	EffectiveFrom uint64 `json:"effectiveFrom"`
+	// EndBlock 为空表示当前速率, endTime 为查询时间
+	EndBlock         *uint64 `json:"endBlock"`
+	EndTime          uint64  `json:"endTime"`
+	Duration         uint64  `json:"duration"`
+	TotalStakedStart string  `json:"totalStakedStart"`
+	TotalStakedEnd   string  `json:"totalStakedEnd"`
+	// StakedSeconds 期内质押总量大于 0 的秒数, 无人质押时不发放奖励
+	StakedSeconds uint64 `json:"stakedSeconds"`
+	Emitted       string `json:"emitted"`
+}
+
+type RewardRateHistory struct {
+	Contract     string             `json:"contract"`
+	Timestamp    uint64             `json:"timestamp"`
+	TotalEmitted string             `json:"totalEmitted"`
+	Periods      []RewardRatePeriod `json:"periods"`
+}
+
+type rewardAccrualService struct{}
+
+func NewRewardAccrualService() RewardAccrualService {
+	return &rewardAccrualService{}
+}
+
+// EarnedAt 重放 timestamp 及之前的事件, 计算 earned(account) 在该时间的值;
+// 结果只覆盖已索引 (已确认) 的区块
+func (r *rewardAccrualService) EarnedAt(ctx context.Context, contractAddress common.Address, account common.Address, timestamp uint64) (*RewardAccrual, error) {
+	events, err := loadAccrualEvents(ctx, contractAddress.Hex(), "block_timestamp <= ?", timestamp)
+	if err != nil {
+		return nil, err
+	}
+	engine := accrual.NewEngine(accrual.DefaultRewardRate)
+	for _, ev := range events {
+		if err := engine.Apply(ev); err != nil {
+			return nil, fmt.Errorf("replay accrual: %w", err)
+		}
+	}
+	user := engine.User(account.Hex())
+	result := &RewardAccrual{
+		Contract:               contractAddress.Hex(),
+		Account:                account.Hex(),
+		Timestamp:              timestamp,
+		Earned:                 engine.Earned(account.Hex(), timestamp).String(),
+		StakedBalance:          user.StakedBalance.String(),
+		Rewards:                user.Rewards.String(),
+		UserRewardPerTokenPaid: user.UserRewardPerTokenPaid.String(),
+		RewardPerToken:         engine.RewardPerToken(timestamp).String(),
+		RewardPerTokenStored:   engine.RewardPerTokenStored().String(),
+		RewardRate:             engine.RewardRate().String(),
+		TotalStaked:            engine.TotalStaked().String(),
+		LastUpdateTime:         engine.LastUpdateTime(),
+		EventCount:             len(events),
+	}
+	if len(events) > 0 {
+		result.LastEventBlock = events[len(events)-1].BlockNumber
+	}
+	return result, nil
+}
+
+// RateHistory 按 RewardRateUpdated 划分速率区间, 重放其他事件统计各区间发放的奖励;
+// 每次结算时 rate * (now - lastUpdateTime) 计入当时生效的速率区间, timestamp 之后的事件不计入
+func (r *rewardAccrualService) RateHistory(ctx context.Context, contractAddress common.Address, timestamp uint64) (*RewardRateHistory, error) {
+	events, err := loadAccrualEvents(ctx, contractAddress.Hex(), "block_timestamp <= ?", timestamp)
+	if err != nil {
+		return nil, err
+	}
+	engine := accrual.NewEngine(accrual.DefaultRewardRate)
+	var periods []RewardRatePeriod
+	var emitted []*big.Int
+	// accrue 结算到 at: 质押总量为 0 时合约 rewardPerToken 不增长
+	accrue := func(at uint64) {
+		last := engine.LastUpdateTime()
+		if len(periods) == 0 || at <= last || engine.TotalStaked().Sign() == 0 {
+			return
+		}
+		seconds := at - last
+		amount := new(big.Int).Mul(engine.RewardRate(), new(big.Int).SetUint64(seconds))
+		emitted[len(emitted)-1].Add(emitted[len(emitted)-1], amount)
+		periods[len(periods)-1].StakedSeconds += seconds
+	}
+	open := func(ev accrual.Event, rate *big.Int, initial bool) {
+		effectiveFrom := ev.Timestamp
+		if !initial && engine.TotalStaked().Sign() > 0 {
+			effectiveFrom = engine.LastUpdateTime()
+		}
+		periods = append(periods, RewardRatePeriod{
+			RewardRate:       rate.String(),
+			Initial:          initial,
+			StartBlock:       ev.BlockNumber,
+			StartTime:        ev.Timestamp,
+			EffectiveFrom:    effectiveFrom,
+			TotalStakedStart: engine.TotalStaked().String(),
+		})
+		emitted = append(emitted, new(big.Int))
+	}
+	closeLast := func(endBlock *uint64, endTime uint64) {
+		if len(periods) == 0 {
+			return
+		}
+		period := &periods[len(periods)-1]
+		period.EndBlock = endBlock
+		period.EndTime = endTime
+		period.Duration = endTime - period.StartTime
+		period.TotalStakedEnd = engine.TotalStaked().String()
+		period.Emitted = emitted[len(emitted)-1].String()
+	}
+	for _, ev := range events {
+		if ev.Kind == accrual.RewardRateUpdated {
+			block := ev.BlockNumber
+			closeLast(&block, ev.Timestamp)
+			open(ev, ev.Amount, false)
+		} else {
+			if len(periods) == 0 {
+				open(ev, accrual.DefaultRewardRate, true)
+			}
+			accrue(ev.Timestamp)
+		}
+		if err := engine.Apply(ev); err != nil {
+			return nil, fmt.Errorf("replay accrual: %w", err)
+		}
+	}
+	// 当前区间按查询时间计入尚未结算的部分
+	if len(periods) > 0 {
+		accrue(timestamp)
+		closeLast(nil, timestamp)
+	}
+
+	total := new(big.Int)
+	for _, amount := range emitted {
+		total.Add(total, amount)
+	}
+	if periods == nil {
+		periods = []RewardRatePeriod{}
+	}
+	return &RewardRateHistory{
+		Contract:     contractAddress.Hex(),
+		Timestamp:    timestamp,
+		TotalEmitted: total.String(),
+		Periods:      periods,
+	}, nil
+}
+
+// loadAccrualEvents 读取合约满足 where 条件的四类事件, 按 (区块, 日志索引) 排序
+func loadAccrualEvents(ctx context.Context, contract string, where string, args ...interface{}) ([]accrual.Event, error) {
+	db := models.DB.WithContext(ctx).Where("contract = ?", contract)
+	var events []accrual.Event
+
+	var staked []models.StakingEventStaked
+	if err := db.Session(&gorm.Session{}).Where(where, args...).Find(&staked).Error; err != nil {
+		return nil, fmt.Errorf("load staked events: %w", err)
+	}
+	for _, row := range staked {
+		events = append(events, accrual.Event{Kind: accrual.Staked, BlockNumber: row.BlockNumber, LogIndex: row.LogIndex, Timestamp: row.BlockTimestamp, User: row.User, Amount: parseAmount(row.Amount)})
+	}
+	var withdrawn []models.StakingEventWithdrawn
+	if err := db.Session(&gorm.Session{}).Where(where, args...).Find(&withdrawn).Error; err != nil {
+		return nil, fmt.Errorf("load withdrawn events: %w", err)
+	}
+	for _, row := range withdrawn {
+		events = append(events, accrual.Event{Kind: accrual.Withdrawn, BlockNumber: row.BlockNumber, LogIndex: row.LogIndex, Timestamp: row.BlockTimestamp, User: row.User, Amount: parseAmount(row.Amount)})
+	}
+	var claimed []models.StakingEventRewardsClaimed
+	if err := db.Session(&gorm.Session{}).Where(where, args...).Find(&claimed).Error; err != nil {
+		return nil, fmt.Errorf("load rewards claimed events: %w", err)
+	}
+	for _, row := range claimed {
+		events = append(events, accrual.Event{Kind: accrual.RewardsClaimed, BlockNumber: row.BlockNumber, LogIndex: row.LogIndex, Timestamp: row.BlockTimestamp, User: row.User, Amount: parseAmount(row.Amount)})
+	}
+	var rates []models.StakingEventRewardRateUpdated
+	if err := db.Session(&gorm.Session{}).Where(where, args...).Find(&rates).Error; err != nil {
+		return nil, fmt.Errorf("load reward rate events: %w", err)
+	}
+	for _, row := range rates {
+		events = append(events, accrual.Event{Kind: accrual.RewardRateUpdated, BlockNumber: row.BlockNumber, LogIndex: row.LogIndex, Timestamp: row.BlockTimestamp, Amount: parseAmount(row.NewRewardRate)})
+	}
+
+	for _, ev := range events {
+		// 升级前入库的事件没有区块时间, 需先重新索引
+		if ev.Timestamp == 0 {
+			return nil, fmt.Errorf("event at block %d has no block timestamp, reindex the contract first", ev.BlockNumber)
+		}
+		if ev.Amount == nil {
+			return nil, fmt.Errorf("event at block %d has invalid amount", ev.BlockNumber)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].BlockNumber != events[j].BlockNumber {
+			return events[i].BlockNumber < events[j].BlockNumber
+		}
+		return events[i].LogIndex < events[j].LogIndex
+	})
+	return events, nil
+}
+
+func parseAmount(s string) *big.Int {
+	amount, ok := new(big.Int).SetString(s, 10)
+	if !ok {
+		return nil
+	}
+	return amount
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	GranularityHour = "hour"
+	GranularityDay  = "day"
+)
+
+// 单次查询最多返回的桶数
+const maxSeriesBuckets = 2000
+
+var granularitySeconds = map[string]uint64{
+	GranularityHour: 3600,
+	GranularityDay:  86400,
+}
+
+type RollupService interface {
+	Series(ctx context.Context, contractAddress common.Address, granularity string, from uint64, to uint64) ([]models.StakingRollup, error)
+	Rebuild(ctx context.Context, contractAddress common.Address) error
+	StartLoop(ctx context.Context, interval time.Duration)
+}
+
+type rollupService struct{}
+
+func NewRollupService() RollupService {
+	return &rollupService{}
+}
+
+// Series 返回 [from, to] 内连续的时间桶, 没有事件的桶流量为 0, tvl 沿用上一个桶
+func (r *rollupService) Series(ctx context.Context, contractAddress common.Address, granularity string, from uint64, to uint64) ([]models.StakingRollup, error) {
+	step, ok := granularitySeconds[granularity]
+	if !ok {
+		return nil, fmt.Errorf("unsupported granularity %q", granularity)
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid time range %d-%d", from, to)
+	}
+	from -= from % step
+	to -= to % step
+	if (to-from)/step+1 > maxSeriesBuckets {
+		return nil, fmt.Errorf("time range too large, at most %d buckets", maxSeriesBuckets)
+	}
+	contract := contractAddress.Hex()
+	db := models.DB.WithContext(ctx).Where("contract = ? and granularity = ?", contract, granularity)
+	var rows []models.StakingRollup
+	if err := db.Session(&gorm.Session{}).Where("bucket_start between ? and ?", from, to).Order("bucket_start").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("query rollup: %w", err)
+	}
+	// 区间开始前最后一个桶的 tvl
+	tvl := "0"
+	var prev models.StakingRollup
+	err := db.Session(&gorm.Session{}).Where("bucket_start < ?", from).Order("bucket_start desc").Limit(1).Find(&prev).Error
+	if err != nil {
+		return nil, fmt.Errorf("query rollup: %w", err)
+	}
+	if prev.ID != 0 {
+		tvl = prev.Tvl
+	}
+	series := make([]models.StakingRollup, 0, (to-from)/step+1)
+	next := 0
+	for bucket := from; bucket <= to; bucket += step {
+		if next < len(rows) && rows[next].BucketStart == bucket {
+			tvl = rows[next].Tvl
+			series = append(series, rows[next])
+			next++
+			continue
+		}
+		series = append(series, models.StakingRollup{
+			Contract:        contract,
+			Granularity:     granularity,
+			BucketStart:     bucket,
+			StakedAmount:    "0",
+			WithdrawnAmount: "0",
+			NetFlow:         "0",
+			ClaimedAmount:   "0",
+			Tvl:             tvl,
+		})
+	}
+	return series, nil
+}
+
+// Rebuild 从头重建合约的全部时间桶
+func (r *rollupService) Rebuild(ctx context.Context, contractAddress common.Address) error {
+	if err := markRollupDirty(models.DB.WithContext(ctx), contractAddress.Hex(), 0); err != nil {
+		return fmt.Errorf("mark rollup dirty: %w", err)
+	}
+	return r.refresh(ctx, contractAddress.Hex())
+}
+
+// StartLoop 定时重建有新事件或被回滚/重新索引的时间桶
+func (r *rollupService) StartLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.runOnce(ctx); err != nil {
+				logger.WithModule("rollup").WithError(err).Error("rollup run failed")
+			}
+		}
+	}
+}
+
+func (r *rollupService) runOnce(ctx context.Context) error {
+	// 新注册的 staking 合约还没有标记, 从头构建
+	var contracts []string
+	err := models.DB.WithContext(ctx).Model(&models.WatchedContract{}).
+		Where("kind = ?", ContractKindStaking).
+		Where("address NOT IN (?)", models.DB.Model(&models.StakingRollupState{}).Select("contract")).
+		Pluck("address", &contracts).Error
+	if err != nil {
+		return err
+	}
+	for _, contract := range contracts {
+		if err := markRollupDirty(models.DB.WithContext(ctx), contract, 0); err != nil {
+			return err
+		}
+	}
+	var dirty []string
+	if err := models.DB.WithContext(ctx).Model(&models.StakingRollupState{}).Where("dirty_from IS NOT NULL").Pluck("contract", &dirty).Error; err != nil {
+		return err
+	}
+	for _, contract := range dirty {
+		if err := r.refresh(ctx, contract); err != nil {
+			logger.WithModule("rollup").WithError(err).WithField("contract", contract).Error("rebuild rollup failed")
+		}
+	}
+	return nil
+}
+
+// refresh 重建 dirty_from 所在天及之后的全部桶; 重建期间有新标记时保留标记, 下次继续
+func (r *rollupService) refresh(ctx context.Context, contract string) error {
+	var state models.StakingRollupState
+	if err := models.DB.WithContext(ctx).Where("contract = ?", contract).First(&state).Error; err != nil {
+		return err
+	}
+	if state.DirtyFrom == nil {
+		return nil
+	}
+	dayStep := granularitySeconds[GranularityDay]
+	start := *state.DirtyFrom - *state.DirtyFrom%dayStep
+	base, err := stakedBefore(ctx, contract, start)
+	if err != nil {
+		return err
+	}
+	events, err := loadRollupEvents(ctx, contract, start)
+	if err != nil {
+		return err
+	}
+	var rows []models.StakingRollup
+	for _, granularity := range []string{GranularityHour, GranularityDay} {
+		rows = append(rows, aggregateRollup(contract, granularity, base, events)...)
+	}
+	err = models.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("contract = ? and bucket_start >= ?", contract, start).Delete(&models.StakingRollup{}).Error; err != nil {
+			return err
+		}
+		if len(rows) > 0 {
+			if err := tx.CreateInBatches(rows, 500).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&models.StakingRollupState{}).
+			Where("contract = ? and version = ?", contract, state.Version).
+			Update("dirty_from", nil).Error
+	})
+	if err != nil {
+		return err
+	}
+	logger.WithModule("rollup").WithFields(logrus.Fields{
+		"contract": contract,
+		"from":     start,
+		"events":   len(events),
+		"buckets":  len(rows),
+	}).Info("rollup rebuilt")
+	return nil
+}
+
+// markRollupDirty 标记 timestamp 之后的桶需要重建, 与事件写入/删除在同一事务调用
+func markRollupDirty(tx *gorm.DB, contract string, timestamp uint64) error {
+	zero := uint64(0)
+	// 首次出现的合约从头构建
+	state := models.StakingRollupState{Contract: contract, DirtyFrom: &zero, Version: 1, UpdatedAt: time.Now()}
+	return tx.Clauses(clause.OnConflict{
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"dirty_from": gorm.Expr("LEAST(COALESCE(dirty_from, ?), ?)", timestamp, timestamp),
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": gorm.Expr("VALUES(updated_at)"),
+		}),
+	}).Create(&state).Error
+}
+
+// rollupEvent 聚合所需的事件字段
+type rollupEvent struct {
+	kind      string
+	user      string
+	amount    *big.Int
+	timestamp uint64
+}
+
+// stakedBefore 时间 start 之前累计的质押减提取, 作为 tvl 起点
+func stakedBefore(ctx context.Context, contract string, start uint64) (*big.Int, error) {
+	total := new(big.Int)
+	for _, item := range []struct {
+		model interface{}
+		sign  int
+	}{
+		{&models.StakingEventStaked{}, 1},
+		{&models.StakingEventWithdrawn{}, -1},
+	} {
+		var sum string
+		err := models.DB.WithContext(ctx).Model(item.model).
+			Select("COALESCE(SUM(CAST(amount AS DECIMAL(65,0))), 0)").
+			Where("contract = ? and block_timestamp < ?", contract, start).
+			Scan(&sum).Error
+		if err != nil {
+			return nil, err
+		}
+		value, ok := new(big.Int).SetString(sum, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid amount sum %q", sum)
+		}
+		if item.sign < 0 {
+			value.Neg(value)
+		}
+		total.Add(total, value)
+	}
+	return total, nil
+}
+
+func loadRollupEvents(ctx context.Context, contract string, start uint64) ([]rollupEvent, error) {
+	var events []rollupEvent
+	for _, item := range []struct {
+		kind  string
+		model interface{}
+	}{
+		{"staked", &models.StakingEventStaked{}},
+		{"withdrawn", &models.StakingEventWithdrawn{}},
+		{"rewards_claimed", &models.StakingEventRewardsClaimed{}},
+	} {
+		var rows []struct {
+			User           string
+			Amount         string
+			BlockTimestamp uint64
+		}
+		err := models.DB.WithContext(ctx).Model(item.model).
+			Select("user, amount, block_timestamp").
+			Where("contract = ? and block_timestamp >= ? and block_timestamp > 0", contract, start).
+			Scan(&rows).Error
+		if err != nil {
+			return nil, fmt.Errorf("load %s events: %w", item.kind, err)
+		}
+		for _, row := range rows {
+			amount, ok := new(big.Int).SetString(row.Amount, 10)
+			if !ok {
+				return nil, fmt.Errorf("invalid %s amount %q", item.kind, row.Amount)
+			}
+			events = append(events, rollupEvent{kind: item.kind, user: row.User, amount: amount, timestamp: row.BlockTimestamp})
+		}
+	}
+	return events, nil
+}
+
+// aggregateRollup 把事件按粒度分桶, tvl 从 base 起按桶顺序累加净流入
+func aggregateRollup(contract string, granularity string, base *big.Int, events []rollupEvent) []models.StakingRollup {
+	type bucketAgg struct {
+		staked, withdrawn, claimed            *big.Int
+		stakeCount, withdrawCount, claimCount uint
+		stakers                               map[string]bool // 桶内有 Staked 事件的地址
+	}
+	step := granularitySeconds[granularity]
+	buckets := make(map[uint64]*bucketAgg)
+	for _, ev := range events {
+		start := ev.timestamp - ev.timestamp%step
+		agg, ok := buckets[start]
+		if !ok {
+			agg = &bucketAgg{staked: new(big.Int), withdrawn: new(big.Int), claimed: new(big.Int), stakers: make(map[string]bool)}
+			buckets[start] = agg
+		}
+		switch ev.kind {
+		case "staked":
+			agg.stakers[ev.user] = true
+			agg.staked.Add(agg.staked, ev.amount)
+			agg.stakeCount++
+		case "withdrawn":
+			agg.withdrawn.Add(agg.withdrawn, ev.amount)
+			agg.withdrawCount++
+		case "rewards_claimed":
+			agg.claimed.Add(agg.claimed, ev.amount)
+			agg.claimCount++
+		}
+	}
+	starts := make([]uint64, 0, len(buckets))
+	for start := range buckets {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+	tvl := new(big.Int).Set(base)
+	now := time.Now()
+	rows := make([]models.StakingRollup, 0, len(starts))
+	for _, start := range starts {
+		agg := buckets[start]
+		net := new(big.Int).Sub(agg.staked, agg.withdrawn)
+		tvl.Add(tvl, net)
+		rows = append(rows, models.StakingRollup{
+			Contract:        contract,
+			Granularity:     granularity,
+			BucketStart:     start,
+			StakedAmount:    agg.staked.String(),
+			WithdrawnAmount: agg.withdrawn.String(),
+			NetFlow:         net.String(),
+			ClaimedAmount:   agg.claimed.String(),
+			Tvl:             tvl.String(),
+			StakeCount:      agg.stakeCount,
+			WithdrawCount:   agg.withdrawCount,
+			ClaimCount:      agg.claimCount,
+			UniqueStakers:   uint(len(agg.stakers)),
+			UpdatedAt:       now,
+		})
+	}
+	return rows
+}
@@ -0,0 +1,31 @@
+package service
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestAggregateRollupCountsStakersOnly(t *testing.T) {
+	events := []rollupEvent{
+		{kind: "staked", user: "0xA", amount: big.NewInt(100), timestamp: 3600},
+		{kind: "staked", user: "0xA", amount: big.NewInt(50), timestamp: 3700},
+		{kind: "staked", user: "0xB", amount: big.NewInt(10), timestamp: 3800},
+		// 只提取或领取的地址不计入
+		{kind: "withdrawn", user: "0xC", amount: big.NewInt(20), timestamp: 3900},
+		{kind: "rewards_claimed", user: "0xD", amount: big.NewInt(5), timestamp: 4000},
+		{kind: "withdrawn", user: "0xA", amount: big.NewInt(30), timestamp: 7200},
+	}
+	rows := aggregateRollup("0xC0", GranularityHour, big.NewInt(1000), events)
+	if len(rows) != 2 {
+		t.Fatalf("got %d buckets, want 2", len(rows))
+	}
+	if rows[0].UniqueStakers != 2 {
+		t.Fatalf("first bucket unique stakers = %d, want 2", rows[0].UniqueStakers)
+	}
+	if rows[0].Tvl != "1140" || rows[0].WithdrawCount != 1 || rows[0].ClaimCount != 1 {
+		t.Fatalf("first bucket tvl %s withdraws %d claims %d, want 1140 1 1", rows[0].Tvl, rows[0].WithdrawCount, rows[0].ClaimCount)
+	}
+	if rows[1].UniqueStakers != 0 || rows[1].Tvl != "1110" {
+		t.Fatalf("second bucket unique stakers %d tvl %s, want 0 1110", rows[1].UniqueStakers, rows[1].Tvl)
+	}
+}
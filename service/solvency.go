@@ -0,0 +1,287 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-solidity-staking/accrual"
+	"go-solidity-staking/gen/erc20"
+	"go-solidity-staking/gen/staking"
+	"go-solidity-staking/logger"
+	"go-solidity-staking/models"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	// LiabilityEngine 按已索引事件重放计算 earned
+	LiabilityEngine = "engine"
+	// LiabilityOnchain 对每个质押用户调用合约 earned
+	LiabilityOnchain = "onchain"
+)
+
+// SolvencyConfig 偿付能力监控参数
+type SolvencyConfig struct {
+	LiabilitySource string
+	WarningRunway   time.Duration // 剩余时间低于该值为 warning
+	CriticalRunway  time.Duration // 剩余时间低于该值或余额不足以覆盖负债为 critical
+	AlertWebhook    string        // 级别变化时 POST 告警, 为空只写日志
+}
+
+type SolvencyService interface {
+	Status(ctx context.Context, contractAddress common.Address) (*models.RewardPoolSnapshot, error)
+	History(ctx context.Context, contractAddress common.Address, pageNum int, pageSize int) ([]models.RewardPoolSnapshot, int64, error)
+	StartLoop(ctx context.Context, interval time.Duration)
+}
+
+type solvencyService struct {
+	client *ethclient.Client
+	cfg    SolvencyConfig
+	http   *http.Client
+	mu     sync.Mutex
+	levels map[string]string // 每个合约上次的告警级别
+}
+
+func NewSolvencyService(client *ethclient.Client, cfg SolvencyConfig) SolvencyService {
+	if cfg.LiabilitySource == "" {
+		cfg.LiabilitySource = LiabilityEngine
+	}
+	return &solvencyService{client: client, cfg: cfg, http: &http.Client{Timeout: 10 * time.Second}, levels: make(map[string]string)}
+}
+
+// Status 在已索引的最新区块上计算奖励池状态, 链上调用与事件重放使用同一区块, 不写入快照
+func (s *solvencyService) Status(ctx context.Context, contractAddress common.Address) (*models.RewardPoolSnapshot, error) {
+	var state models.SyncState
+	err := models.DB.WithContext(ctx).Where("name = ?", WatchTarget{Kind: ContractKindStaking, Address: contractAddress}.syncKey()).First(&state).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("contract %s has not been indexed", contractAddress.Hex())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load sync state: %w", err)
+	}
+	header, err := s.client.HeaderByNumber(ctx, new(big.Int).SetUint64(state.BlockNumber))
+	if err != nil {
+		return nil, fmt.Errorf("get header %d: %w", state.BlockNumber, err)
+	}
+	block := BlockRef{Number: header.Number}
+	newStaking, err := staking.NewStaking(contractAddress, s.client)
+	if err != nil {
+		return nil, fmt.Errorf("new staking contract: %w", err)
+	}
+	rewardRate, err := newStaking.RewardRate(block.callOpts(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("rewardRate call: %w", block.callErr(err))
+	}
+	stakingToken, err := newStaking.SStakingToken(block.callOpts(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("s_stakingToken call: %w", block.callErr(err))
+	}
+	rewardToken, err := newStaking.SRewardToken(block.callOpts(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("s_rewardToken call: %w", block.callErr(err))
+	}
+	token, err := erc20.NewErc20(rewardToken, s.client)
+	if err != nil {
+		return nil, fmt.Errorf("new erc20 contract: %w", err)
+	}
+	balance, err := token.BalanceOf(block.callOpts(ctx), contractAddress)
+	if err != nil {
+		return nil, fmt.Errorf("balanceOf call: %w", block.callErr(err))
+	}
+
+	events, err := loadAccrualEvents(ctx, contractAddress.Hex(), "block_number <= ?", header.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	engine := accrual.NewEngine(accrual.DefaultRewardRate)
+	for _, ev := range events {
+		if err := engine.Apply(ev); err != nil {
+			return nil, fmt.Errorf("replay accrual: %w", err)
+		}
+	}
+	totalStaked := engine.TotalStaked()
+	// 质押代币与奖励代币相同时, 余额中包含用户本金
+	if stakingToken == rewardToken {
+		balance.Sub(balance, totalStaked)
+	}
+	liabilities := new(big.Int)
+	if s.cfg.LiabilitySource == LiabilityOnchain {
+		var users []string
+		if err := models.DB.WithContext(ctx).Model(&models.StakingPosition{}).Where("contract = ?", contractAddress.Hex()).Pluck("user", &users).Error; err != nil {
+			return nil, fmt.Errorf("load stakers: %w", err)
+		}
+		for _, user := range users {
+			earned, err := newStaking.Earned(block.callOpts(ctx), common.HexToAddress(user))
+			if err != nil {
+				return nil, fmt.Errorf("earned call: %w", block.callErr(err))
+			}
+			liabilities.Add(liabilities, earned)
+		}
+	} else {
+		for _, account := range engine.Accounts() {
+			liabilities.Add(liabilities, engine.Earned(account, header.Time))
+		}
+	}
+	available := new(big.Int).Sub(balance, liabilities)
+
+	snapshot := &models.RewardPoolSnapshot{
+		Contract:      contractAddress.Hex(),
+		BlockNumber:   header.Number.Uint64(),
+		BlockTime:     header.Time,
+		RewardToken:   rewardToken.Hex(),
+		RewardBalance: balance.String(),
+		Liabilities:   liabilities.String(),
+		Available:     available.String(),
+		RewardRate:    rewardRate.String(),
+		TotalStaked:   totalStaked.String(),
+		Level:         models.SolvencyOK,
+		CreatedAt:     time.Now(),
+	}
+	// 没有质押时合约不发放奖励, 剩余时间不受限
+	if rewardRate.Sign() > 0 && totalStaked.Sign() > 0 {
+		runway := uint64(0)
+		if available.Sign() > 0 {
+			seconds := new(big.Int).Div(available, rewardRate)
+			if seconds.IsUint64() {
+				runway = seconds.Uint64()
+			} else {
+				runway = ^uint64(0)
+			}
+		}
+		snapshot.RunwaySeconds = &runway
+	}
+	snapshot.Level = s.level(available, snapshot.RunwaySeconds)
+	return snapshot, nil
+}
+
+func (s *solvencyService) level(available *big.Int, runway *uint64) string {
+	if available.Sign() < 0 {
+		return models.SolvencyCritical
+	}
+	if runway == nil {
+		return models.SolvencyOK
+	}
+	if *runway < uint64(s.cfg.CriticalRunway.Seconds()) {
+		return models.SolvencyCritical
+	}
+	if *runway < uint64(s.cfg.WarningRunway.Seconds()) {
+		return models.SolvencyWarning
+	}
+	return models.SolvencyOK
+}
+
+func (s *solvencyService) History(ctx context.Context, contractAddress common.Address, pageNum int, pageSize int) ([]models.RewardPoolSnapshot, int64, error) {
+	query := models.DB.WithContext(ctx).Model(&models.RewardPoolSnapshot{}).Where("contract = ?", contractAddress.Hex())
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("count snapshots: %w", err)
+	}
+	var list []models.RewardPoolSnapshot
+	err := query.Order("id desc").Offset((pageNum - 1) * pageSize).Limit(pageSize).Find(&list).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("list snapshots: %w", err)
+	}
+	return list, total, nil
+}
+
+// StartLoop 定时为已启用的 staking 合约写入快照, 告警级别变化时发出告警
+func (s *solvencyService) StartLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var contracts []string
+			err := models.DB.WithContext(ctx).Model(&models.WatchedContract{}).
+				Where("enabled = ? and kind = ?", true, ContractKindStaking).
+				Pluck("address", &contracts).Error
+			if err != nil {
+				logger.WithModule("solvency").WithError(err).Error("load contracts failed")
+				continue
+			}
+			for _, contract := range contracts {
+				if err := s.check(ctx, common.HexToAddress(contract)); err != nil {
+					logger.WithModule("solvency").WithError(err).WithField("contract", contract).Error("solvency check failed")
+				}
+			}
+		}
+	}
+}
+
+func (s *solvencyService) check(ctx context.Context, contractAddress common.Address) error {
+	snapshot, err := s.Status(ctx, contractAddress)
+	if err != nil {
+		return err
+	}
+	if err := models.DB.WithContext(ctx).Create(snapshot).Error; err != nil {
+		return fmt.Errorf("save snapshot: %w", err)
+	}
+	s.mu.Lock()
+	previous, seen := s.levels[snapshot.Contract]
+	s.levels[snapshot.Contract] = snapshot.Level
+	s.mu.Unlock()
+	// 启动后首次为 ok 不告警, 之后只在级别变化时告警
+	if previous == snapshot.Level || (!seen && snapshot.Level == models.SolvencyOK) {
+		return nil
+	}
+	s.alert(ctx, previous, snapshot)
+	return nil
+}
+
+func (s *solvencyService) alert(ctx context.Context, previous string, snapshot *models.RewardPoolSnapshot) {
+	entry := logger.WithModule("solvency").WithFields(logrus.Fields{
+		"contract":       snapshot.Contract,
+		"block":          snapshot.BlockNumber,
+		"previous":       previous,
+		"level":          snapshot.Level,
+		"reward_balance": snapshot.RewardBalance,
+		"liabilities":    snapshot.Liabilities,
+		"runway_seconds": snapshot.RunwaySeconds,
+	})
+	switch snapshot.Level {
+	case models.SolvencyCritical:
+		entry.Error("reward pool critical")
+	case models.SolvencyWarning:
+		entry.Warn("reward pool running low")
+	default:
+		entry.Info("reward pool recovered")
+	}
+	if s.cfg.AlertWebhook == "" {
+		return
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"event":    "reward_pool_solvency",
+		"previous": previous,
+		"snapshot": snapshot,
+	})
+	if err != nil {
+		entry.WithError(err).Error("encode alert failed")
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.AlertWebhook, bytes.NewReader(body))
+	if err != nil {
+		entry.WithError(err).Error("new alert request failed")
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.http.Do(req)
+	if err != nil {
+		entry.WithError(err).Error("send alert failed")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		entry.WithField("status", resp.StatusCode).Error("alert webhook rejected")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/ecdsa"
 	"fmt"
 	"go-solidity-staking/gen/staking"
+	"go-solidity-staking/models"
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
@@ -18,14 +19,22 @@ type StakingService interface {
 	WithdrawStakedTokens(ctx context.Context, contractAddress common.Address, privateKey *ecdsa.PrivateKey, amount *big.Int) (*types.Transaction, error)
 	GetReward(ctx context.Context, contractAddress common.Address, privateKey *ecdsa.PrivateKey) (*types.Transaction, error)
 	UpdateRewardRate(ctx context.Context, contractAddress common.Address, privateKey *ecdsa.PrivateKey, newRewardRate *big.Int) (*types.Transaction, error)
-	Earned(ctx context.Context, contractAddress common.Address, account common.Address) (*big.Int, error)
-	StakedBalance(ctx context.Context, contractAddress common.Address, account common.Address) (*big.Int, error)
-	RewardPerToken(ctx context.Context, contractAddress common.Address) (*big.Int, error)
-	RewardPerTokenStored(ctx context.Context, contractAddress common.Address) (*big.Int, error)
-	RewardRate(ctx context.Context, contractAddress common.Address) (*big.Int, error)
-	LastUpdateTime(ctx context.Context, contractAddress common.Address) (*big.Int, error)
-	UserRewardPerTokenPaid(ctx context.Context, contractAddress common.Address, account common.Address) (*big.Int, error)
-	Rewards(ctx context.Context, contractAddress common.Address, account common.Address) (*big.Int, error)
+	Earned(ctx context.Context, contractAddress common.Address, account common.Address, block BlockRef) (*big.Int, error)
+	StakedBalance(ctx context.Context, contractAddress common.Address, account common.Address, block BlockRef) (*big.Int, error)
+	RewardPerToken(ctx context.Context, contractAddress common.Address, block BlockRef) (*big.Int, error)
+	RewardPerTokenStored(ctx context.Context, contractAddress common.Address, block BlockRef) (*big.Int, error)
+	RewardRate(ctx context.Context, contractAddress common.Address, block BlockRef) (*big.Int, error)
+	LastUpdateTime(ctx context.Context, contractAddress common.Address, block BlockRef) (*big.Int, error)
+	UserRewardPerTokenPaid(ctx context.Context, contractAddress common.Address, account common.Address, block BlockRef) (*big.Int, error)
+	Rewards(ctx context.Context, contractAddress common.Address, account common.Address, block BlockRef) (*big.Int, error)
+	OwnershipHistory(ctx context.Context, contractAddress common.Address, block BlockRef) (*OwnershipHistory, error)
+}
+
+// OwnershipHistory 合约 owner 变更记录与当前 owner
+type OwnershipHistory struct {
+	Contract     string                                    `json:"contract"`
+	CurrentOwner string                                    `json:"currentOwner"`
+	Transfers    []models.StakingEventOwnershipTransferred `json:"transfers"`
 }
 
 type stakingService struct {
@@ -128,98 +137,124 @@ func (s *stakingService) UpdateRewardRate(ctx context.Context, contractAddress c
 	return tx, nil
 }
 
-func (s *stakingService) Earned(ctx context.Context, contractAddress common.Address, account common.Address) (*big.Int, error) {
+func (s *stakingService) Earned(ctx context.Context, contractAddress common.Address, account common.Address, block BlockRef) (*big.Int, error) {
 	newStaking, err := staking.NewStaking(contractAddress, s.client)
 	if err != nil {
 		return nil, fmt.Errorf("new staking contract: %w", err)
 	}
-	value, err := newStaking.Earned(&bind.CallOpts{Context: ctx}, account)
+	value, err := newStaking.Earned(block.callOpts(ctx), account)
 	if err != nil {
-		return nil, fmt.Errorf("earned call: %w", err)
+		return nil, fmt.Errorf("earned call: %w", block.callErr(err))
 	}
 	return value, nil
 }
 
-func (s *stakingService) StakedBalance(ctx context.Context, contractAddress common.Address, account common.Address) (*big.Int, error) {
+func (s *stakingService) StakedBalance(ctx context.Context, contractAddress common.Address, account common.Address, block BlockRef) (*big.Int, error) {
 	newStaking, err := staking.NewStaking(contractAddress, s.client)
 	if err != nil {
 		return nil, fmt.Errorf("new staking contract: %w", err)
 	}
-	value, err := newStaking.StakedBalance(&bind.CallOpts{Context: ctx}, account)
+	value, err := newStaking.StakedBalance(block.callOpts(ctx), account)
 	if err != nil {
-		return nil, fmt.Errorf("stakedBalance call: %w", err)
+		return nil, fmt.Errorf("stakedBalance call: %w", block.callErr(err))
 	}
 	return value, nil
 }
 
-func (s *stakingService) RewardPerToken(ctx context.Context, contractAddress common.Address) (*big.Int, error) {
+func (s *stakingService) RewardPerToken(ctx context.Context, contractAddress common.Address, block BlockRef) (*big.Int, error) {
 	newStaking, err := staking.NewStaking(contractAddress, s.client)
 	if err != nil {
 		return nil, fmt.Errorf("new staking contract: %w", err)
 	}
-	value, err := newStaking.RewardPerToken(&bind.CallOpts{Context: ctx})
+	value, err := newStaking.RewardPerToken(block.callOpts(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("rewardPerToken call: %w", err)
+		return nil, fmt.Errorf("rewardPerToken call: %w", block.callErr(err))
 	}
 	return value, nil
 }
 
-func (s *stakingService) RewardPerTokenStored(ctx context.Context, contractAddress common.Address) (*big.Int, error) {
+func (s *stakingService) RewardPerTokenStored(ctx context.Context, contractAddress common.Address, block BlockRef) (*big.Int, error) {
 	newStaking, err := staking.NewStaking(contractAddress, s.client)
 	if err != nil {
 		return nil, fmt.Errorf("new staking contract: %w", err)
 	}
-	value, err := newStaking.RewardPerTokenStored(&bind.CallOpts{Context: ctx})
+	value, err := newStaking.RewardPerTokenStored(block.callOpts(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("rewardPerTokenStored call: %w", err)
+		return nil, fmt.Errorf("rewardPerTokenStored call: %w", block.callErr(err))
 	}
 	return value, nil
 }
 
-func (s *stakingService) RewardRate(ctx context.Context, contractAddress common.Address) (*big.Int, error) {
+func (s *stakingService) RewardRate(ctx context.Context, contractAddress common.Address, block BlockRef) (*big.Int, error) {
 	newStaking, err := staking.NewStaking(contractAddress, s.client)
 	if err != nil {
 		return nil, fmt.Errorf("new staking contract: %w", err)
 	}
-	value, err := newStaking.RewardRate(&bind.CallOpts{Context: ctx})
+	value, err := newStaking.RewardRate(block.callOpts(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("rewardRate call: %w", err)
+		return nil, fmt.Errorf("rewardRate call: %w", block.callErr(err))
 	}
 	return value, nil
 }
 
-func (s *stakingService) LastUpdateTime(ctx context.Context, contractAddress common.Address) (*big.Int, error) {
+func (s *stakingService) LastUpdateTime(ctx context.Context, contractAddress common.Address, block BlockRef) (*big.Int, error) {
 	newStaking, err := staking.NewStaking(contractAddress, s.client)
 	if err != nil {
 		return nil, fmt.Errorf("new staking contract: %w", err)
 	}
-	value, err := newStaking.LastUpdateTime(&bind.CallOpts{Context: ctx})
+	value, err := newStaking.LastUpdateTime(block.callOpts(ctx))
 	if err != nil {
-		return nil, fmt.Errorf("lastUpdateTime call: %w", err)
+		return nil, fmt.Errorf("lastUpdateTime call: %w", block.callErr(err))
 	}
 	return value, nil
 }
 
-func (s *stakingService) UserRewardPerTokenPaid(ctx context.Context, contractAddress common.Address, account common.Address) (*big.Int, error) {
+func (s *stakingService) UserRewardPerTokenPaid(ctx context.Context, contractAddress common.Address, account common.Address, block BlockRef) (*big.Int, error) {
 	newStaking, err := staking.NewStaking(contractAddress, s.client)
 	if err != nil {
 		return nil, fmt.Errorf("new staking contract: %w", err)
 	}
-	value, err := newStaking.UserRewardPerTokenPaid(&bind.CallOpts{Context: ctx}, account)
+	value, err := newStaking.UserRewardPerTokenPaid(block.callOpts(ctx), account)
 	if err != nil {
-		return nil, fmt.Errorf("userRewardPerTokenPaid call: %w", err)
+		return nil, fmt.Errorf("userRewardPerTokenPaid call: %w", block.callErr(err))
 	}
 	return value, nil
 }
 
-func (s *stakingService) Rewards(ctx context.Context, contractAddress common.Address, account common.Address) (*big.Int, error) {
+func (s *stakingService) Rewards(ctx context.Context, contractAddress common.Address, account common.Address, block BlockRef) (*big.Int, error) {
 	newStaking, err := staking.NewStaking(contractAddress, s.client)
 	if err != nil {
 		return nil, fmt.Errorf("new staking contract: %w", err)
 	}
-	value, err := newStaking.Rewards(&bind.CallOpts{Context: ctx}, account)
+	value, err := newStaking.Rewards(block.callOpts(ctx), account)
 	if err != nil {
-		return nil, fmt.Errorf("rewards call: %w", err)
+		return nil, fmt.Errorf("rewards call: %w", block.callErr(err))
 	}
 	return value, nil
 }
+
+func (s *stakingService) OwnershipHistory(ctx context.Context, contractAddress common.Address, block BlockRef) (*OwnershipHistory, error) {
+	newStaking, err := staking.NewStaking(contractAddress, s.client)
+	if err != nil {
+		return nil, fmt.Errorf("new staking contract: %w", err)
+	}
+	owner, err := newStaking.Owner(block.callOpts(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("owner call: %w", block.callErr(err))
+	}
+	var transfers []models.StakingEventOwnershipTransferred
+	query := models.DB.WithContext(ctx).Where("contract = ?", contractAddress.Hex())
+	// 指定高度时只返回该区块及之前的变更
+	if height, ok := block.Height(); ok {
+		query = query.Where("block_number <= ?", height)
+	}
+	err = query.Order("block_number, log_index").Find(&transfers).Error
+	if err != nil {
+		return nil, fmt.Errorf("query ownership transfers: %w", err)
+	}
+	return &OwnershipHistory{
+		Contract:     contractAddress.Hex(),
+		CurrentOwner: owner.Hex(),
+		Transfers:    transfers,
+	}, nil
+}
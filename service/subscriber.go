@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-solidity-staking/logger"
+	"time"
+
+	ethereum "github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	resubscribeMinBackoff = time.Second
+	resubscribeMaxBackoff = 30 * time.Second
+)
+
+var (
+	errSubscriptionClosed = errors.New("subscription closed")
+	errNoHeadSubscription = errors.New("client does not support new head subscription")
+)
+
+// headSubscriber ethclient (ws) 与模拟链都实现了新区块订阅
+type headSubscriber interface {
+	SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error)
+}
+
+// StartSubscribeLoop 订阅模式: 订阅新区块, 每个新区块触发一次按检查点的回放,
+// 事件只在达到确认数后按 (区块, 日志索引) 顺序写入, 重组由回放路径的检测回滚;
+// 断线后退避重连, 重连前先补齐缺口; interval 内没有新区块时也回放一次
+func (l *listenerService) StartSubscribeLoop(ctx context.Context, targets []WatchTarget, starkBlock uint64, confirmations uint64, interval time.Duration) {
+	backfill := func() error {
+		return l.ReplayFromLast(ctx, targets, starkBlock, confirmations)
+	}
+	backoff := resubscribeMinBackoff
+	for {
+		// 补齐断线期间的区块
+		if err := backfill(); err != nil {
+			logger.WithModule("listener").WithError(err).Error("subscribe backfill failed")
+		}
+		started := time.Now()
+		err := l.subscribeHeads(ctx, interval, backfill)
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > resubscribeMaxBackoff {
+			backoff = resubscribeMinBackoff
+		}
+		logger.WithModule("listener").WithError(err).WithFields(logrus.Fields{
+			"contracts": len(targets),
+			"backoff":   backoff.String(),
+		}).Warn("subscription dropped, resubscribing")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > resubscribeMaxBackoff {
+			backoff = resubscribeMaxBackoff
+		}
+	}
+}
+
+// subscribeHeads 每收到一个新区块回放一次, 回放较慢时合并积压的区块通知
+func (l *listenerService) subscribeHeads(ctx context.Context, interval time.Duration, backfill func() error) error {
+	subscriber, ok := l.client.(headSubscriber)
+	if !ok {
+		return errNoHeadSubscription
+	}
+	heads := make(chan *types.Header, 16)
+	sub, err := subscriber.SubscribeNewHead(ctx, heads)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case err := <-sub.Err():
+			return subscriptionErr(err)
+		case <-heads:
+			drainHeads(heads)
+		case <-ticker.C:
+		}
+		if err := backfill(); err != nil {
+			logger.WithModule("listener").WithError(err).Error("subscribe replay failed")
+		}
+	}
+}
+
+func drainHeads(heads chan *types.Header) {
+	for {
+		select {
+		case <-heads:
+		default:
+			return
+		}
+	}
+}
+
+func subscriptionErr(err error) error {
+	if err == nil {
+		return errSubscriptionClosed
+	}
+	return err
+}
@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-solidity-staking/gen/erc20"
+	"go-solidity-staking/gen/staking"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	tokenSelf    = "self"    // ERC20 事件, 合约本身即代币
+	tokenStaking = "staking" // staking 合约的质押代币
+	tokenReward  = "reward"  // staking 合约的奖励代币
+)
+
+// tokenMeta 代币地址、符号与精度
+type tokenMeta struct {
+	address  common.Address
+	symbol   string
+	decimals uint8
+}
+
+// tokenCache 事件金额对应的代币信息, 合约中不可变, 解析后缓存
+type tokenCache struct {
+	client *ethclient.Client
+	mu     sync.Mutex
+	tokens map[string]*tokenMeta // key: 合约地址 + 角色
+}
+
+func newTokenCache(client *ethclient.Client) *tokenCache {
+	return &tokenCache{client: client, tokens: make(map[string]*tokenMeta)}
+}
+
+func (t *tokenCache) resolve(ctx context.Context, contract common.Address, role string) (*tokenMeta, error) {
+	key := contract.Hex() + ":" + role
+	t.mu.Lock()
+	meta, ok := t.tokens[key]
+	t.mu.Unlock()
+	if ok {
+		return meta, nil
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	address := contract
+	if role != tokenSelf {
+		newStaking, err := staking.NewStaking(contract, t.client)
+		if err != nil {
+			return nil, fmt.Errorf("new staking contract: %w", err)
+		}
+		if role == tokenReward {
+			address, err = newStaking.SRewardToken(opts)
+		} else {
+			address, err = newStaking.SStakingToken(opts)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("token address call: %w", err)
+		}
+	}
+	token, err := erc20.NewErc20(address, t.client)
+	if err != nil {
+		return nil, fmt.Errorf("new erc20 contract: %w", err)
+	}
+	decimals, err := token.Decimals(opts)
+	if err != nil {
+		return nil, fmt.Errorf("decimals call: %w", err)
+	}
+	symbol, err := token.Symbol(opts)
+	if err != nil {
+		return nil, fmt.Errorf("symbol call: %w", err)
+	}
+	meta = &tokenMeta{address: address, symbol: symbol, decimals: decimals}
+	t.mu.Lock()
+	t.tokens[key] = meta
+	t.mu.Unlock()
+	return meta, nil
+}
+
+// formatUnits 最小单位按 decimals 转为十进制字符串, 去掉末尾的 0
+func formatUnits(amount string, decimals uint8) string {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return ""
+	}
+	if decimals == 0 {
+		return value.String()
+	}
+	unit := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(decimals)), nil)
+	whole, frac := new(big.Int).QuoRem(value, unit, new(big.Int))
+	if frac.Sign() == 0 {
+		return whole.String()
+	}
+	fracStr := fmt.Sprintf("%0*s", int(decimals), frac.String())
+	return whole.String() + "." + strings.TrimRight(fracStr, "0")
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-solidity-staking/accrual"
+	"go-solidity-staking/gen/erc20"
+	"go-solidity-staking/gen/staking"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+const (
+	secondsPerYear = 365 * 86400
+	// APY 按每天复投一次计算
+	compoundsPerYear = 365
+)
+
+type YieldService interface {
+	Yield(ctx context.Context, contractAddress common.Address, window uint64) (*Yield, error)
+}
+
+// Yield 年化收益, apr/apy 为小数 (0.12 即 12%), 没有质押时为 null
+type Yield struct {
+	Contract               string   `json:"contract"`
+	StakingToken           string   `json:"stakingToken"`
+	RewardToken            string   `json:"rewardToken"`
+	StakingTokenPrice      string   `json:"stakingTokenPrice"`
+	RewardTokenPrice       string   `json:"rewardTokenPrice"`
+	RewardRate             string   `json:"rewardRate"`
+	TotalStaked            string   `json:"totalStaked"`
+	Timestamp              uint64   `json:"timestamp"`
+	Apr                    *float64 `json:"apr"`
+	Apy                    *float64 `json:"apy"`
+	TrailingWindow         uint64   `json:"trailingWindow"`
+	TrailingRewardPerToken string   `json:"trailingRewardPerToken"`
+	TrailingApr            *float64 `json:"trailingApr"`
+	TrailingApy            *float64 `json:"trailingApy"`
+}
+
+type yieldService struct {
+	client *ethclient.Client
+	prices PriceSource
+}
+
+func NewYieldService(client *ethclient.Client, prices PriceSource) YieldService {
+	return &yieldService{client: client, prices: prices}
+}
+
+// Yield 当前 APR 按链上 rewardRate 与已索引的质押总量计算;
+// 过去 window 秒的 APR 按重放得到的 rewardPerToken 增量计算, 即整段时间持续质押的实际收益
+func (y *yieldService) Yield(ctx context.Context, contractAddress common.Address, window uint64) (*Yield, error) {
+	now := uint64(time.Now().Unix())
+	if window == 0 || window > now {
+		return nil, fmt.Errorf("invalid trailing window %d", window)
+	}
+	newStaking, err := staking.NewStaking(contractAddress, y.client)
+	if err != nil {
+		return nil, fmt.Errorf("new staking contract: %w", err)
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	rewardRate, err := newStaking.RewardRate(opts)
+	if err != nil {
+		return nil, fmt.Errorf("rewardRate call: %w", err)
+	}
+	stakingToken, err := newStaking.SStakingToken(opts)
+	if err != nil {
+		return nil, fmt.Errorf("s_stakingToken call: %w", err)
+	}
+	rewardToken, err := newStaking.SRewardToken(opts)
+	if err != nil {
+		return nil, fmt.Errorf("s_rewardToken call: %w", err)
+	}
+	stakingValue, stakingPrice, err := y.unitValue(ctx, stakingToken)
+	if err != nil {
+		return nil, err
+	}
+	rewardValue, rewardPrice, err := y.unitValue(ctx, rewardToken)
+	if err != nil {
+		return nil, err
+	}
+	// 1 个奖励代币最小单位折合多少个质押代币最小单位
+	ratio := new(big.Float).Quo(rewardValue, stakingValue)
+
+	events, err := loadAccrualEvents(ctx, contractAddress.Hex(), "block_timestamp <= ?", now)
+	if err != nil {
+		return nil, err
+	}
+	start := now - window
+	engine := accrual.NewEngine(accrual.DefaultRewardRate)
+	var startRewardPerToken *big.Int
+	for _, ev := range events {
+		if startRewardPerToken == nil && ev.Timestamp > start {
+			startRewardPerToken = engine.RewardPerToken(start)
+		}
+		if err := engine.Apply(ev); err != nil {
+			return nil, fmt.Errorf("replay accrual: %w", err)
+		}
+	}
+	if startRewardPerToken == nil {
+		startRewardPerToken = engine.RewardPerToken(start)
+	}
+	trailing := new(big.Int).Sub(engine.RewardPerToken(now), startRewardPerToken)
+	totalStaked := engine.TotalStaked()
+
+	result := &Yield{
+		Contract:               contractAddress.Hex(),
+		StakingToken:           stakingToken.Hex(),
+		RewardToken:            rewardToken.Hex(),
+		StakingTokenPrice:      stakingPrice.Text('f', -1),
+		RewardTokenPrice:       rewardPrice.Text('f', -1),
+		RewardRate:             rewardRate.String(),
+		TotalStaked:            totalStaked.String(),
+		Timestamp:              now,
+		TrailingWindow:         window,
+		TrailingRewardPerToken: trailing.String(),
+	}
+	if totalStaked.Sign() > 0 {
+		// rewardRate * 一年秒数 / totalStaked
+		apr := new(big.Float).SetInt(new(big.Int).Mul(rewardRate, big.NewInt(secondsPerYear)))
+		apr.Quo(apr, new(big.Float).SetInt(totalStaked))
+		result.Apr, result.Apy = annualize(apr.Mul(apr, ratio))
+	}
+	// rewardPerToken 以 1e18 为精度, 折算为一年
+	trailingApr := new(big.Float).SetInt(new(big.Int).Mul(trailing, big.NewInt(secondsPerYear)))
+	trailingApr.Quo(trailingApr, new(big.Float).SetInt(new(big.Int).Mul(big.NewInt(1e18), new(big.Int).SetUint64(window))))
+	result.TrailingApr, result.TrailingApy = annualize(trailingApr.Mul(trailingApr, ratio))
+	return result, nil
+}
+
+// unitValue 代币一个最小单位的价格与代币单价
+func (y *yieldService) unitValue(ctx context.Context, token common.Address) (*big.Float, *big.Float, error) {
+	price, err := y.prices.Price(ctx, token)
+	if err != nil {
+		return nil, nil, fmt.Errorf("price of %s: %w", token.Hex(), err)
+	}
+	if price.Sign() <= 0 {
+		return nil, nil, fmt.Errorf("price of %s must be positive", token.Hex())
+	}
+	newErc20, err := erc20.NewErc20(token, y.client)
+	if err != nil {
+		return nil, nil, fmt.Errorf("new erc20 contract: %w", err)
+	}
+	decimals, err := newErc20.Decimals(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return nil, nil, fmt.Errorf("decimals call: %w", err)
+	}
+	unit := new(big.Float).Mul(price, scaleDown(big.NewInt(1), decimals))
+	return unit, price, nil
+}
+
+// annualize 返回 apr 与按天复投的 apy
+func annualize(apr *big.Float) (*float64, *float64) {
+	aprValue, _ := apr.Float64()
+	apyValue := math.Pow(1+aprValue/compoundsPerYear, compoundsPerYear) - 1
+	return &aprValue, &apyValue
+}